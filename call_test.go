@@ -37,7 +37,7 @@ func TestHttpCall(t *testing.T) {
 	})
 
 	t.Run("With variables", func(t *testing.T) {
-		query := `query Test($value: Int){
+		query := `query Test($value: Float){
 					mirror(value: $value)
 				}`
 		variables := map[string]interface{}{"value": 1.1}
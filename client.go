@@ -28,7 +28,8 @@ func WithDecoder(d Decoder) ClientOption {
 }
 
 type CallOptions struct {
-	Header http.Header
+	Header        http.Header
+	OperationName string
 }
 
 type CallOption func(*CallOptions)
@@ -39,6 +40,13 @@ func WithHeader(h http.Header) CallOption {
 	}
 }
 
+// WithOperationName selects the operation to execute when the query contains multiple operations.
+func WithOperationName(name string) CallOption {
+	return func(o *CallOptions) {
+		o.OperationName = name
+	}
+}
+
 type Client struct {
 	HttpClient *http.Client
 
@@ -63,19 +71,21 @@ func NewHttpClient(client *http.Client, url string, header http.Header, opts ...
 }
 
 func (c *Client) Do(query string, variables, response interface{}, opts ...CallOption) error {
-	rb := struct {
-		Query     string
-		Variables interface{}
-	}{
-		Query:     query,
-		Variables: variables,
-	}
-
 	var opt CallOptions
 	for _, op := range opts {
 		op(&opt)
 	}
 
+	rb := struct {
+		Query         string
+		Variables     interface{}
+		OperationName string `json:"operationName,omitempty"`
+	}{
+		Query:         query,
+		Variables:     variables,
+		OperationName: opt.OperationName,
+	}
+
 	hr := struct {
 		Data   json.RawMessage  `json:"data"`
 		Errors []*jerrors.Error `json:"errors"`
@@ -0,0 +1,181 @@
+package jaal
+
+// This file contains the event sources, which deliver the events that subscriptions are resolved with. The
+// events are routed by type to the subscriptions selecting the root field of the same name.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"gocloud.dev/pubsub"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// ErrSourceClosed is returned by a MemorySource once it is closed.
+var ErrSourceClosed = errors.New("event source closed")
+
+// Event is an event delivered by an EventSource.
+type Event struct {
+	// Type is the name of the root subscription field the event is delivered to. The events without a type are
+	// delivered to every subscription.
+	Type    string
+	Payload []byte
+}
+
+// EventSource delivers the events of subscriptions.
+type EventSource interface {
+	// Receive blocks until the next event is available. It returns an error once the source stops.
+	Receive(ctx context.Context) (*Event, error)
+}
+
+// PubSubSource returns an EventSource delivering the messages of a gocloud.dev subscription. The type of the
+// events is the "type" metadata of the messages.
+func PubSubSource(s *pubsub.Subscription) EventSource {
+	return &pubSubSource{subscription: s}
+}
+
+type pubSubSource struct {
+	subscription *pubsub.Subscription
+}
+
+func (s *pubSubSource) Receive(ctx context.Context) (*Event, error) {
+	msg, err := s.subscription.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg.Ack()
+
+	return &Event{
+		Type:    msg.Metadata["type"],
+		Payload: msg.Body,
+	}, nil
+}
+
+// MemorySource is an EventSource delivering the events published in process.
+type MemorySource struct {
+	events chan *Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewMemorySource returns an open MemorySource.
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		events: make(chan *Event),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish delivers an event to the subscriptions. It blocks until the event is received, and returns an error
+// when ctx is done or the source is closed first.
+func (s *MemorySource) Publish(ctx context.Context, event *Event) error {
+	select {
+	case s.events <- event:
+		return nil
+	case <-s.done:
+		return ErrSourceClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive implements EventSource.
+func (s *MemorySource) Receive(ctx context.Context) (*Event, error) {
+	select {
+	case event := <-s.events:
+		return event, nil
+	case <-s.done:
+		return nil, ErrSourceClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops the source, which ends the subscriptions relying on it.
+func (s *MemorySource) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// rootFields returns the names of the root fields selected by a subscription, which are the types of the events
+// it receives.
+func rootFields(selectionSet *graphql.SelectionSet) []string {
+	var fields []string
+	for _, selection := range selectionSet.Selections {
+		fields = append(fields, selection.Name)
+	}
+	for _, fragment := range selectionSet.Fragments {
+		fields = append(fields, rootFields(fragment.Fragment.SelectionSet)...)
+	}
+	return fields
+}
+
+// routed checks if an event is delivered to a subscription selecting fields.
+func routed(event *Event, fields []string) bool {
+	if event.Type == "" {
+		return true
+	}
+	for _, field := range fields {
+		if field == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the filter of the events relevant to a subscription, according to the filters of the root
+// fields it selects, including through fragments. An event is relevant when a root field it is routed to has no
+// filter, or has a filter accepting it. The filter is nil when every event is relevant.
+func (h *handler) filter(ctx context.Context, query *graphql.Query) func(*Event) bool {
+	object, ok := h.schema.Subscription.(*graphql.Object)
+	if !ok {
+		return nil
+	}
+	selections, err := graphql.Flatten(query.SelectionSet)
+	if err != nil {
+		return nil
+	}
+
+	filtered := false
+	for _, selection := range selections {
+		if field, ok := object.Fields[selection.Name]; ok && field.Filter != nil {
+			filtered = true
+		}
+	}
+	if !filtered {
+		return nil
+	}
+
+	return func(event *Event) bool {
+		for _, selection := range selections {
+			if event.Type != "" && event.Type != selection.Name {
+				continue
+			}
+			field, ok := object.Fields[selection.Name]
+			if !ok || field.Filter == nil || safeFilter(ctx, selection.Name, field, selection.Args, event.Payload) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// safeFilter calls the filter of the field name, and rejects the event when it panics, so that the router keeps
+// delivering the events to the other subscriptions.
+func safeFilter(ctx context.Context, name string, field *graphql.Field, args interface{}, payload []byte) (ok bool) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			fmt.Printf("filter of %s: panic: %v\n%s", name, panicErr, buf)
+			ok = false
+		}
+	}()
+	return field.Filter(ctx, args, payload)
+}
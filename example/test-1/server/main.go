@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/appointy/idgen"
@@ -108,30 +111,40 @@ func (s *server) registerSubscription(schema *schemabuilder.Schema) {
 	}) (*channel, error) {
 		var ch channel
 		if err := gob.NewDecoder(bytes.NewReader(source.Payload)).Decode(&ch); err != nil {
-			panic(err)
+			return nil, err
 		}
-		if args.In.Name == ch.Name {
-			return &ch, nil
+		return &ch, nil
+	}, schemabuilder.Filter(func(ctx context.Context, args struct {
+		In channelStreamReq
+	}, payload []byte) bool {
+		var ch channel
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&ch); err != nil {
+			return false
 		}
-		return nil, graphql.ErrNoUpdate
-	})
+		return args.In.Name == ch.Name
+	}))
 
 	obj.FieldFunc("postStream", func(source *schemabuilder.Subscription, args struct {
 		In postStreamReq
 	}) (*post, error) {
 		var p post
 		if err := gob.NewDecoder(bytes.NewReader(source.Payload)).Decode(&p); err != nil {
-			panic(err)
+			return nil, err
 		}
-		if args.In.Tag == p.Tag {
-			return &post{
-				Id:    idgen.New("post"),
-				Title: p.Title,
-				Tag:   p.Tag,
-			}, nil
+		return &post{
+			Id:    idgen.New("post"),
+			Title: p.Title,
+			Tag:   p.Tag,
+		}, nil
+	}, schemabuilder.Filter(func(ctx context.Context, args struct {
+		In postStreamReq
+	}, payload []byte) bool {
+		var p post
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&p); err != nil {
+			return false
 		}
-		return nil, graphql.ErrNoUpdate
-	})
+		return args.In.Tag == p.Tag
+	}))
 }
 
 // schema builds the graphql schema.
@@ -284,7 +297,23 @@ func main() {
 		}
 	}()
 	f()
-	if err := http.ListenAndServe(":8081", nil); err != nil {
+
+	// The subscriptions are completed before the server stops, so that the clients do not wait for them.
+	srv := &http.Server{Addr: ":8081"}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := handler.Shutdown(ctx); err != nil {
+			fmt.Println(err)
+		}
+		if err := srv.Shutdown(ctx); err != nil {
+			fmt.Println(err)
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		fmt.Println(err)
 	}
 }
@@ -0,0 +1,233 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// executeBatch executes the selectionSet on every source at once. Sources found at the same path of
+// the response are executed together, so that the fields having a BatchResolver are resolved with a
+// single call for all the sources instead of one call per source.
+//
+// Every source is found at the matching entry of paths in the response. The returned slices hold the
+// result and the error for every source, with the errors nested relative to their source.
+func (e *execution) executeBatch(ctx context.Context, typ Type, sources []interface{}, selectionSet *SelectionSet, paths []*path) ([]interface{}, []error) {
+	switch typ := typ.(type) {
+	case *NonNull:
+		return e.executeBatch(ctx, typ.Type, sources, selectionSet, paths)
+	case *Object:
+		return e.executeObjectBatch(ctx, typ, sources, selectionSet, paths)
+	case *List:
+		return e.executeListBatch(ctx, typ, sources, selectionSet, paths)
+	default:
+		items := make([]interface{}, len(sources))
+		errs := make([]error, len(sources))
+		e.parallel(len(sources), func(i int) {
+			items[i], errs[i] = e.execute(ctx, typ, sources[i], selectionSet, paths[i])
+		})
+		return items, errs
+	}
+}
+
+// executeListBatch executes the elements of all the lists in sources together.
+func (e *execution) executeListBatch(ctx context.Context, typ *List, sources []interface{}, selectionSet *SelectionSet, paths []*path) ([]interface{}, []error) {
+	items := make([]interface{}, len(sources))
+	errs := make([]error, len(sources))
+
+	var elems []interface{}
+	var elemPaths []*path
+	offsets := make([]int, len(sources)+1)
+	for i, source := range sources {
+		offsets[i] = len(elems)
+		slice := reflect.ValueOf(source)
+		if slice.IsNil() {
+			continue
+		}
+		for j := 0; j < slice.Len(); j++ {
+			elems = append(elems, slice.Index(j).Interface())
+			elemPaths = append(elemPaths, paths[i].with(fmt.Sprint(j)))
+		}
+	}
+	offsets[len(sources)] = len(elems)
+
+	values, valueErrs := e.executeBatch(ctx, typ.Type, elems, selectionSet, elemPaths)
+
+	for i := range sources {
+		list := values[offsets[i]:offsets[i+1]]
+		for j, err := range valueErrs[offsets[i]:offsets[i+1]] {
+			if err == nil {
+				continue
+			}
+			if err := e.handleError(paths[i], fmt.Sprint(j), typ.Type, err); err != nil {
+				errs[i] = err
+				break
+			}
+			list[j] = nil
+		}
+
+		if len(list) == 0 {
+			items[i] = emptyList
+			continue
+		}
+		items[i] = list
+	}
+
+	return items, errs
+}
+
+// executeObjectBatch resolves every selection of the object for all the sources.
+func (e *execution) executeObjectBatch(ctx context.Context, typ *Object, sources []interface{}, selectionSet *SelectionSet, paths []*path) ([]interface{}, []error) {
+	items := make([]interface{}, len(sources))
+	errs := make([]error, len(sources))
+
+	// Null sources resolve to null, the rest are resolved together.
+	var indices []int
+	var present []interface{}
+	var presentPaths []*path
+	for i, source := range sources {
+		value := reflect.ValueOf(source)
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			continue
+		}
+		indices = append(indices, i)
+		present = append(present, source)
+		presentPaths = append(presentPaths, paths[i])
+	}
+	if len(present) == 0 {
+		return items, errs
+	}
+
+	selections, err := Flatten(selectionSet)
+	if err != nil {
+		for _, i := range indices {
+			errs[i] = err
+		}
+		return items, errs
+	}
+
+	// results[s][i] holds the value of selection s for the present source i.
+	results := make([][]interface{}, len(selections))
+	resultErrs := make([][]error, len(selections))
+	included := make([]bool, len(selections))
+
+	e.parallel(len(selections), func(s int) {
+		selection := selections[s]
+		results[s] = make([]interface{}, len(present))
+		resultErrs[s] = make([]error, len(present))
+
+		fieldTyp := typenameType
+		if field, ok := typ.Fields[selection.Name]; ok {
+			fieldTyp = field.Type
+		}
+
+		ok, err := shouldIncludeNode(selection.Directives)
+		if err != nil {
+			for i := range present {
+				resultErrs[s][i] = err
+			}
+		} else if !ok {
+			return
+		} else if selection.Name == "__typename" {
+			for i := range present {
+				results[s][i] = typ.Name
+			}
+		} else {
+			fieldPaths := make([]*path, len(present))
+			for i := range present {
+				fieldPaths[i] = presentPaths[i].with(selection.Alias)
+			}
+			results[s], resultErrs[s] = e.resolveBatch(ctx, typ.Fields[selection.Name], present, selection, fieldPaths)
+		}
+		included[s] = true
+
+		for i, err := range resultErrs[s] {
+			if err != nil {
+				results[s][i], resultErrs[s][i] = nil, e.handleError(presentPaths[i], selection.Alias, fieldTyp, err)
+			}
+		}
+	})
+
+	for i, index := range indices {
+		fields := make(map[string]interface{}, len(selections))
+		for s, selection := range selections {
+			if err := resultErrs[s][i]; err != nil {
+				errs[index] = err
+				break
+			}
+			if included[s] {
+				fields[selection.Alias] = results[s][i]
+			}
+		}
+		if errs[index] == nil {
+			items[index] = fields
+		}
+	}
+
+	return items, errs
+}
+
+// resolveBatch resolves the field for all the sources and executes the selection on the values, which are
+// found at the matching entry of paths.
+func (e *execution) resolveBatch(ctx context.Context, field *Field, sources []interface{}, selection *Selection, paths []*path) ([]interface{}, []error) {
+	values := make([]interface{}, len(sources))
+	errs := make([]error, len(sources))
+
+	// Lazy fields are resolved later in a separate pass, so there is nothing to batch.
+	if field.LazyExecution {
+		e.parallel(len(sources), func(i int) {
+			values[i], errs[i] = e.resolveAndExecute(ctx, field, sources[i], selection, paths[i])
+		})
+		return values, errs
+	}
+
+	if selection.UseBatch && field.BatchResolver != nil {
+		resolved, err := safeExecuteBatchResolver(ctx, field, sources, selection.Args, selection.SelectionSet)
+		if err == nil && len(resolved) != len(sources) {
+			err = fmt.Errorf("batch resolver returned %d values for %d sources", len(resolved), len(sources))
+		}
+		if err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+			return values, errs
+		}
+		copy(values, resolved)
+	} else {
+		e.parallel(len(sources), func(i int) {
+			values[i], errs[i] = safeExecuteResolver(ctx, field, sources[i], selection.Args, selection.SelectionSet)
+		})
+	}
+
+	// Execute the selection set on the values which were resolved successfully.
+	var indices []int
+	var resolved []interface{}
+	var resolvedPaths []*path
+	for i, err := range errs {
+		if err == nil {
+			indices = append(indices, i)
+			resolved = append(resolved, values[i])
+			resolvedPaths = append(resolvedPaths, paths[i])
+		}
+	}
+
+	items, itemErrs := e.executeBatch(ctx, field.Type, resolved, selection.SelectionSet, resolvedPaths)
+	for j, i := range indices {
+		values[i], errs[i] = items[j], itemErrs[j]
+	}
+
+	return values, errs
+}
+
+func safeExecuteBatchResolver(ctx context.Context, field *Field, sources []interface{}, args interface{}, selectionSet *SelectionSet) (result []interface{}, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			result, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
+		}
+	}()
+	return field.BatchResolver(ctx, sources, args, selectionSet)
+}
@@ -0,0 +1,149 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+	"go.appointy.com/jaal/schemabuilder"
+	"google.golang.org/grpc/codes"
+)
+
+func TestBatchExecution(t *testing.T) {
+	type Department struct {
+		Id   string
+		Head string
+	}
+
+	type Employee struct {
+		Name         string
+		DepartmentId string
+	}
+
+	departments := map[string]*Department{
+		"d1": {Id: "d1", Head: "e1"},
+		"d2": {Id: "d2", Head: "e3"},
+	}
+	employees := []*Employee{
+		{Name: "e1", DepartmentId: "d1"},
+		{Name: "e2", DepartmentId: "d1"},
+		{Name: "e3", DepartmentId: "d2"},
+		{Name: "e4", DepartmentId: ""},
+	}
+
+	var departmentCalls, headCalls int
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("employees", func() []*Employee {
+		return employees
+	})
+	query.FieldFunc("employee", func() *Employee {
+		return employees[2]
+	})
+
+	employee := schema.Object("Employee", Employee{})
+	employee.FieldFunc("name", func(in *Employee) string {
+		return in.Name
+	})
+	employee.BatchFieldFunc("department", func(ctx context.Context, in []*Employee) []*Department {
+		departmentCalls++
+		out := make([]*Department, len(in))
+		for i, e := range in {
+			out[i] = departments[e.DepartmentId]
+		}
+		return out
+	})
+	employee.BatchFieldFunc("failing", func(in []Employee, args struct{ Fail bool }) ([]string, error) {
+		if args.Fail {
+			return nil, errors.New("batch failed")
+		}
+		return make([]string, len(in)), nil
+	})
+
+	department := schema.Object("Department", Department{})
+	department.FieldFunc("id", func(in *Department) string {
+		return in.Id
+	})
+	department.BatchFieldFunc("head", func(in []*Department) []string {
+		headCalls++
+		out := make([]string, len(in))
+		for i, d := range in {
+			out[i] = d.Head
+		}
+		return out
+	})
+
+	builtSchema := schema.MustBuild()
+
+	execute := func(queryString string) (interface{}, error) {
+		departmentCalls, headCalls = 0, 0
+
+		q, err := graphql.Parse(queryString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		result, err := execute(`{
+			employees {
+				name
+				department { id head }
+			}
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"employees": []interface{}{
+				map[string]interface{}{"name": "e1", "department": map[string]interface{}{"id": "d1", "head": "e1"}},
+				map[string]interface{}{"name": "e2", "department": map[string]interface{}{"id": "d1", "head": "e1"}},
+				map[string]interface{}{"name": "e3", "department": map[string]interface{}{"id": "d2", "head": "e3"}},
+				map[string]interface{}{"name": "e4", "department": nil},
+			},
+		}, result)
+		assert.Equal(t, 1, departmentCalls)
+		assert.Equal(t, 1, headCalls)
+	})
+
+	t.Run("single object", func(t *testing.T) {
+		result, err := execute(`{
+			employee {
+				department { head }
+			}
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"employee": map[string]interface{}{"department": map[string]interface{}{"head": "e3"}},
+		}, result)
+		assert.Equal(t, 1, departmentCalls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := execute(`{
+			employees {
+				name
+				failing(fail: true)
+			}
+		}`)
+		assert.Equal(t, &jerrors.Error{
+			Message:    "batch failed",
+			Paths:      []string{"employees", "0", "failing"},
+			Extensions: &jerrors.Extension{Code: codes.Unknown.String()},
+		}, err)
+	})
+}
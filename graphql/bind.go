@@ -0,0 +1,121 @@
+package graphql
+
+// This file contains the binding of variables, which turns a prepared query into a query that can be validated
+// and executed. The prepared query is copied rather than modified, so it can be shared by concurrent requests.
+
+// variable is the placeholder of a variable in the arguments of a prepared query.
+type variable string
+
+// Bind returns a copy of the query with the variables in its arguments and directives replaced by their values
+// in vars, or by their default values. The query itself is not modified.
+func (q *Query) Bind(vars map[string]interface{}) *Query {
+	// Add the default values of the variables which were not provided.
+	var defaultedVars map[string]interface{}
+	for _, definition := range q.Variables {
+		// Ignore default if the value exists.
+		if definition.DefaultValue == nil || vars[definition.Name] != nil {
+			continue
+		}
+
+		// Lazily initialize defaultedVars if needed.
+		if defaultedVars == nil {
+			defaultedVars = make(map[string]interface{})
+			for k, v := range vars {
+				defaultedVars[k] = v
+			}
+		}
+
+		defaultedVars[definition.Name] = definition.DefaultValue
+	}
+	if defaultedVars != nil {
+		vars = defaultedVars
+	}
+
+	b := &binder{vars: vars, fragments: make(map[*FragmentDefinition]*FragmentDefinition)}
+	bound := *q
+	bound.SelectionSet = b.selectionSet(q.SelectionSet)
+	bound.variables = vars
+	return &bound
+}
+
+// binder copies the selection sets of a query, binding their variables. Fragment definitions are copied once,
+// so that the spreads of a fragment still share its definition.
+type binder struct {
+	vars      map[string]interface{}
+	fragments map[*FragmentDefinition]*FragmentDefinition
+}
+
+func (b *binder) selectionSet(selectionSet *SelectionSet) *SelectionSet {
+	if selectionSet == nil {
+		return nil
+	}
+
+	bound := &SelectionSet{}
+	if selectionSet.Selections != nil {
+		bound.Selections = make([]*Selection, 0, len(selectionSet.Selections))
+	}
+	if selectionSet.Fragments != nil {
+		bound.Fragments = make([]*FragmentSpread, 0, len(selectionSet.Fragments))
+	}
+	for _, selection := range selectionSet.Selections {
+		bound.Selections = append(bound.Selections, &Selection{
+			Name:         selection.Name,
+			Alias:        selection.Alias,
+			Args:         b.value(selection.Args),
+			SelectionSet: b.selectionSet(selection.SelectionSet),
+			Directives:   b.directives(selection.Directives),
+		})
+	}
+	for _, fragment := range selectionSet.Fragments {
+		bound.Fragments = append(bound.Fragments, &FragmentSpread{
+			Fragment:   b.fragment(fragment.Fragment),
+			Directives: b.directives(fragment.Directives),
+		})
+	}
+	return bound
+}
+
+func (b *binder) fragment(fragment *FragmentDefinition) *FragmentDefinition {
+	if bound, ok := b.fragments[fragment]; ok {
+		return bound
+	}
+
+	bound := &FragmentDefinition{Name: fragment.Name, On: fragment.On}
+	b.fragments[fragment] = bound
+	bound.SelectionSet = b.selectionSet(fragment.SelectionSet)
+	return bound
+}
+
+func (b *binder) directives(directives []*Directive) []*Directive {
+	if directives == nil {
+		return nil
+	}
+
+	bound := make([]*Directive, 0, len(directives))
+	for _, directive := range directives {
+		bound = append(bound, &Directive{Name: directive.Name, Args: b.value(directive.Args)})
+	}
+	return bound
+}
+
+// value returns a copy of an argument value, like those generated by json.Unmarshal, with its variables bound.
+func (b *binder) value(value interface{}) interface{} {
+	switch value := value.(type) {
+	case variable:
+		return b.vars[string(value)]
+	case map[string]interface{}:
+		bound := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			bound[k] = b.value(v)
+		}
+		return bound
+	case []interface{}:
+		bound := make([]interface{}, 0, len(value))
+		for _, v := range value {
+			bound = append(bound, b.value(v))
+		}
+		return bound
+	default:
+		return value
+	}
+}
@@ -151,3 +151,27 @@ func TestClone(t *testing.T) {
 
 	assert.Equal(t, string(result1), string(result2))
 }
+
+func TestCloneFilter(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("ping", func() string {
+		return "pong"
+	})
+	schema.Subscription().FieldFunc("tagged", func(source *schemabuilder.Subscription, args struct{ Tag string }) string {
+		return string(source.Payload)
+	}, schemabuilder.Filter(func(ctx context.Context, args struct{ Tag string }, payload []byte) bool {
+		return string(payload) == args.Tag
+	}))
+
+	built := schema.Clone().MustBuild()
+	field := built.Subscription.(*graphql.Object).Fields["tagged"]
+	if field.Filter == nil {
+		t.Fatal("expected the filter to be cloned")
+	}
+	args, err := field.ParseArguments(map[string]interface{}{"tag": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, field.Filter(context.Background(), args, []byte("b")))
+	assert.False(t, field.Filter(context.Background(), args, []byte("a")))
+}
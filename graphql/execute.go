@@ -6,13 +6,63 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"go.appointy.com/jaal/jerrors"
 )
 
+// Executor executes the queries against a schema. The zero value executes
+// every selection serially.
 type Executor struct {
+	// MaxConcurrency is the maximum number of resolvers that may run in parallel
+	// while executing a single query. Sibling fields and list elements are
+	// resolved concurrently when it is greater than one. The root fields of a
+	// mutation are always executed serially.
+	MaxConcurrency int
+
+	// PartialResults enables the error handling described by the spec. A nullable field which
+	// fails resolves to null and its error is collected, while a failing non-null field nulls its
+	// nearest nullable parent. Execute then returns the data along with a *jerrors.MultiError holding
+	// every collected error. Otherwise the first error fails the whole query.
+	PartialResults bool
+}
+
+// execution holds the state of a single query execution, so that an Executor
+// can be shared between concurrent requests.
+type execution struct {
+	// sem limits the number of goroutines spawned for this execution. It is nil
+	// when the execution is serial.
+	sem chan struct{}
+
+	partial bool
+
+	mu      sync.Mutex
 	iterate bool
+	errors  []*jerrors.Error
+}
+
+// path is the location of a value in the response, stored as a linked list from the value to the root.
+type path struct {
+	parent *path
+	key    string
+}
+
+func (p *path) with(key string) *path {
+	return &path{parent: p, key: key}
+}
+
+func (p *path) keys() []string {
+	var keys []string
+	for ; p != nil; p = p.parent {
+		keys = append(keys, p.key)
+	}
+	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	return keys
 }
 
 type computationOutput struct {
@@ -23,24 +73,147 @@ type computationOutput struct {
 
 var ErrNoUpdate = errors.New("no update")
 
+// typenameType is the type of the __typename meta field.
+var typenameType Type = &NonNull{Type: &Scalar{Type: "String"}}
+
+// Execute executes the query against the typ, resolving the root fields on source.
 func (e *Executor) Execute(ctx context.Context, typ Type, source interface{}, query *Query) (interface{}, error) {
-	response, err := e.execute(ctx, typ, source, query.SelectionSet)
-	if err != nil {
-		return nil, err
+	ex := &execution{partial: e.PartialResults}
+	if e.MaxConcurrency > 1 {
+		ex.sem = make(chan struct{}, e.MaxConcurrency-1)
 	}
 
-	for e.iterate {
-		e.iterate = false
+	var response interface{}
+	var err error
+	if obj, ok := typ.(*Object); ok && query.Kind == "mutation" {
+		response, err = ex.executeObject(ctx, obj, source, query.SelectionSet, nil, true)
+	} else {
+		response, err = ex.execute(ctx, typ, source, query.SelectionSet, nil)
+	}
+	if err != nil {
+		if err == ErrNoUpdate || !ex.partial {
+			return nil, err
+		}
+		ex.collect(nil, err)
+		response = nil
+	}
 
-		if err := e.lateExecution(ctx, response); err != nil {
+	for response != nil && ex.shouldIterate() {
+		if err := ex.lateExecution(ctx, response, nil); err != nil {
 			return nil, err
 		}
 	}
 
+	if len(ex.errors) > 0 {
+		sortErrors(ex.errors)
+		return response, &jerrors.MultiError{Errors: ex.errors}
+	}
+
 	return response, nil
 }
 
-func (e *Executor) execute(ctx context.Context, typ Type, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+// handleError handles the err of the value found under key of the parent at p. The error fails
+// the parent unless partial results are enabled and the value is nullable, in which case the
+// error is collected and nil is returned so that the value becomes null.
+func (e *execution) handleError(p *path, key string, typ Type, err error) error {
+	if err == ErrNoUpdate {
+		return err
+	}
+
+	err = jerrors.NestErrorPaths(err, key)
+	if _, nonNull := typ.(*NonNull); !e.partial || nonNull {
+		return err
+	}
+
+	e.collect(p, err)
+	return nil
+}
+
+// collect records err, whose paths are relative to p.
+func (e *execution) collect(p *path, err error) {
+	converted := jerrors.ConvertError(err)
+	collected := &jerrors.Error{
+		Message:    converted.Message,
+		Extensions: converted.Extensions,
+		Paths:      append(p.keys(), converted.Paths...),
+	}
+	if collected.Paths == nil {
+		collected.Paths = []string{}
+	}
+
+	e.mu.Lock()
+	e.errors = append(e.errors, collected)
+	e.mu.Unlock()
+}
+
+// sortErrors orders the errors by their paths, so that the output does not depend on the order in
+// which concurrent resolvers failed. List indices are compared numerically.
+func sortErrors(errs []*jerrors.Error) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i].Paths, errs[j].Paths
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] == b[k] {
+				continue
+			}
+			x, errX := strconv.Atoi(a[k])
+			y, errY := strconv.Atoi(b[k])
+			if errX == nil && errY == nil {
+				return x < y
+			}
+			return a[k] < b[k]
+		}
+		return len(a) < len(b)
+	})
+}
+
+// shouldIterate reports whether a lazy field was found since the last call, and resets the flag.
+func (e *execution) shouldIterate() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	iterate := e.iterate
+	e.iterate = false
+	return iterate
+}
+
+func (e *execution) markIterate() {
+	e.mu.Lock()
+	e.iterate = true
+	e.mu.Unlock()
+}
+
+// parallel calls fn for every index in [0, n). The calls run concurrently as long as the
+// execution has free slots, and inline on the calling goroutine otherwise, so nested
+// parallel calls can never deadlock waiting for each other.
+func (e *execution) parallel(n int, fn func(i int)) {
+	if e.sem == nil || n < 2 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case e.sem <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-e.sem
+					wg.Done()
+				}()
+				fn(i)
+			}(i)
+		default:
+			fn(i)
+		}
+	}
+	wg.Wait()
+}
+
+// execute executes the selectionSet on the source of type typ, which is found at p in the response.
+func (e *execution) execute(ctx context.Context, typ Type, source interface{}, selectionSet *SelectionSet, p *path) (interface{}, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -57,15 +230,15 @@ func (e *Executor) execute(ctx context.Context, typ Type, source interface{}, se
 		}
 		return nil, errors.New("enum is not valid")
 	case *Union:
-		return e.executeUnion(ctx, typ, source, selectionSet)
+		return e.executeUnion(ctx, typ, source, selectionSet, p)
 	case *Interface:
-		return e.executeInterface(ctx, typ, source, selectionSet)
+		return e.executeInterface(ctx, typ, source, selectionSet, p)
 	case *Object:
-		return e.executeObject(ctx, typ, source, selectionSet)
+		return e.executeObject(ctx, typ, source, selectionSet, p, false)
 	case *List:
-		return e.executeList(ctx, typ, source, selectionSet)
+		return e.executeList(ctx, typ, source, selectionSet, p)
 	case *NonNull:
-		return e.execute(ctx, typ.Type, source, selectionSet)
+		return e.execute(ctx, typ.Type, source, selectionSet, p)
 	default:
 		panic(typ)
 	}
@@ -83,7 +256,7 @@ func unwrap(v interface{}) interface{} {
 	return i.Interface()
 }
 
-func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+func (e *execution) executeUnion(ctx context.Context, typ *Union, source interface{}, selectionSet *SelectionSet, p *path) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
@@ -115,7 +288,7 @@ func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interfac
 			if fragment.Fragment.On != typString {
 				continue
 			}
-			resolved, err := e.executeObject(ctx, graphqlTyp, inner.Interface(), fragment.Fragment.SelectionSet)
+			resolved, err := e.executeObject(ctx, graphqlTyp, inner.Interface(), fragment.Fragment.SelectionSet, p, false)
 			if err != nil {
 				if err == ErrNoUpdate {
 					return nil, err
@@ -135,8 +308,9 @@ func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interfac
 	return fields, nil
 }
 
-// executeObject executes an object query
-func (e *Executor) executeObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+// executeObject executes an object query. When serial is set, the selections are
+// resolved one after another regardless of the concurrency of the execution.
+func (e *execution) executeObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet, p *path, serial bool) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
@@ -147,39 +321,64 @@ func (e *Executor) executeObject(ctx context.Context, typ *Object, source interf
 		return nil, err
 	}
 
-	fields := make(map[string]interface{})
+	resolved := make([]interface{}, len(selections))
+	included := make([]bool, len(selections))
+	errs := make([]error, len(selections))
 
 	// for every selection, resolve the value and store it in the output object
-	for _, selection := range selections {
+	resolve := func(i int) {
+		selection := selections[i]
+		fieldTyp := typenameType
+		if field, ok := typ.Fields[selection.Name]; ok {
+			fieldTyp = field.Type
+		}
+
 		if ok, err := shouldIncludeNode(selection.Directives); err != nil {
-			if err == ErrNoUpdate {
-				return nil, err
-			}
-			return nil, jerrors.NestErrorPaths(err, selection.Alias)
+			errs[i] = e.handleError(p, selection.Alias, fieldTyp, err)
+			return
 		} else if !ok {
-			continue
+			return
 		}
+		included[i] = true
 
 		if selection.Name == "__typename" {
-			fields[selection.Alias] = typ.Name
-			continue
+			resolved[i] = typ.Name
+			return
 		}
 
 		field := typ.Fields[selection.Name]
-		resolved, err := e.resolveAndExecute(ctx, field, source, selection)
-		if err != nil {
-			if err == ErrNoUpdate {
-				return nil, err
+		resolved[i], errs[i] = e.resolveAndExecute(ctx, field, source, selection, p.with(selection.Alias))
+		if errs[i] != nil {
+			resolved[i], errs[i] = nil, e.handleError(p, selection.Alias, field.Type, errs[i])
+		}
+	}
+
+	if serial {
+		for i := range selections {
+			resolve(i)
+			if errs[i] != nil {
+				break
 			}
-			return nil, jerrors.NestErrorPaths(err, selection.Alias)
 		}
-		fields[selection.Alias] = resolved
+	} else {
+		e.parallel(len(selections), resolve)
+	}
+
+	fields := make(map[string]interface{}, len(selections))
+	for i, selection := range selections {
+		if err := errs[i]; err != nil {
+			return nil, err
+		}
+		if included[i] {
+			fields[selection.Alias] = resolved[i]
+		}
 	}
 
 	return fields, nil
 }
 
-func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+// resolveAndExecute resolves the field on source and executes the selection on the value, which is found at p.
+func (e *execution) resolveAndExecute(ctx context.Context, field *Field, source interface{}, selection *Selection, p *path) (interface{}, error) {
 	value, err := safeExecuteResolver(ctx, field, source, selection.Args, selection.SelectionSet)
 	if err != nil {
 		return nil, err
@@ -187,7 +386,7 @@ func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source i
 
 	// If a field returns function, then do not execute the function at the moment
 	if field.LazyExecution {
-		e.iterate = true
+		e.markIterate()
 		return &computationOutput{
 			Function:  value,
 			Field:     field,
@@ -195,7 +394,7 @@ func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source i
 		}, nil
 	}
 
-	return e.execute(ctx, field.Type, value, selection.SelectionSet)
+	return e.execute(ctx, field.Type, value, selection.SelectionSet, p)
 }
 
 func safeExecuteResolver(ctx context.Context, field *Field, source, args interface{}, selectionSet *SelectionSet) (result interface{}, err error) {
@@ -213,33 +412,37 @@ func safeExecuteResolver(ctx context.Context, field *Field, source, args interfa
 var emptyList = []interface{}{}
 
 // executeList executes a set query
-func (e *Executor) executeList(ctx context.Context, typ *List, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+func (e *execution) executeList(ctx context.Context, typ *List, source interface{}, selectionSet *SelectionSet, p *path) (interface{}, error) {
 	if reflect.ValueOf(source).IsNil() {
 		return emptyList, nil
 	}
 
 	// iterate over arbitrary slice types using reflect
 	slice := reflect.ValueOf(source)
-	items := make([]interface{}, slice.Len())
+	sources := make([]interface{}, slice.Len())
+	paths := make([]*path, slice.Len())
+	for i := range sources {
+		sources[i] = slice.Index(i).Interface()
+		paths[i] = p.with(fmt.Sprint(i))
+	}
+
+	// resolve every element in the slice together, so that batch fields are resolved once
+	items, errs := e.executeBatch(ctx, typ.Type, sources, selectionSet, paths)
 
-	// resolve every element in the slice
-	for i := 0; i < slice.Len(); i++ {
-		value := slice.Index(i)
-		resolved, err := e.execute(ctx, typ.Type, value.Interface(), selectionSet)
+	for i, err := range errs {
 		if err != nil {
-			if err == ErrNoUpdate {
+			if err := e.handleError(p, fmt.Sprint(i), typ.Type, err); err != nil {
 				return nil, err
 			}
-			return nil, jerrors.NestErrorPaths(err, fmt.Sprint(i))
+			items[i] = nil
 		}
-		items[i] = resolved
 	}
 
 	return items, nil
 }
 
 // executeInterface resolves an interface query
-func (e *Executor) executeInterface(ctx context.Context, typ *Interface, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+func (e *execution) executeInterface(ctx context.Context, typ *Interface, source interface{}, selectionSet *SelectionSet, p *path) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
@@ -272,26 +475,35 @@ func (e *Executor) executeInterface(ctx context.Context, typ *Interface, source
 		if err != nil {
 			return nil, err
 		}
+		resolved := make([]interface{}, len(selections))
+		included := make([]bool, len(selections))
+		errs := make([]error, len(selections))
+
 		// for every selection, resolve the value and store it in the output object
-		for _, selection := range selections {
+		e.parallel(len(selections), func(i int) {
+			selection := selections[i]
 			if selection.Name == "__typename" {
-				fields[selection.Alias] = graphqlTyp.Name
-				continue
+				resolved[i], included[i] = graphqlTyp.Name, true
+				return
 			}
 			field, ok := graphqlTyp.Fields[selection.Name]
 			if !ok {
-				continue
+				return
 			}
-			value := reflect.ValueOf(source).Elem()
-			value = value.FieldByName(typString)
-			resolved, err := e.resolveAndExecute(ctx, field, value.Interface(), selection)
-			if err != nil {
-				if err == ErrNoUpdate {
-					return nil, err
-				}
-				return nil, jerrors.NestErrorPaths(err, selection.Alias)
+			included[i] = true
+			resolved[i], errs[i] = e.resolveAndExecute(ctx, field, inner.Interface(), selection, p.with(selection.Alias))
+			if errs[i] != nil {
+				resolved[i], errs[i] = nil, e.handleError(p, selection.Alias, field.Type, errs[i])
+			}
+		})
+
+		for i, selection := range selections {
+			if err := errs[i]; err != nil {
+				return nil, err
+			}
+			if included[i] {
+				fields[selection.Alias] = resolved[i]
 			}
-			fields[selection.Alias] = resolved
 		}
 	}
 
@@ -340,11 +552,15 @@ func shouldIncludeNode(directives []*Directive) (bool, error) {
 	return true, nil
 }
 
-func (e *Executor) lateExecution(ctx context.Context, response interface{}) error {
+// lateExecution executes the lazy fields found in the response, which is found at p.
+//
+// With partial results, a failing lazy field always resolves to null, as the response above it has
+// already been built.
+func (e *execution) lateExecution(ctx context.Context, response interface{}, p *path) error {
 	list, ok := response.([]interface{})
 	if ok {
-		for _, element := range list {
-			if err := e.lateExecution(ctx, element); err != nil {
+		for i, element := range list {
+			if err := e.lateExecution(ctx, element, p.with(fmt.Sprint(i))); err != nil {
 				return err
 			}
 		}
@@ -358,15 +574,19 @@ func (e *Executor) lateExecution(ctx context.Context, response interface{}) erro
 	for key, value := range data {
 		output, ok := value.(*computationOutput)
 		if !ok {
-			if err := e.lateExecution(ctx, value); err != nil {
+			if err := e.lateExecution(ctx, value, p.with(key)); err != nil {
 				return err
 			}
 			continue
 		}
 
-		resolved, err := e.resolveAndExecuteFunction(ctx, output)
+		resolved, err := e.resolveAndExecuteFunction(ctx, output, p.with(key))
 		if err != nil {
-			return err
+			if err == ErrNoUpdate || !e.partial {
+				return err
+			}
+			e.collect(p, jerrors.NestErrorPaths(err, key))
+			resolved = nil
 		}
 
 		data[key] = resolved
@@ -375,11 +595,11 @@ func (e *Executor) lateExecution(ctx context.Context, response interface{}) erro
 	return nil
 }
 
-func (e *Executor) resolveAndExecuteFunction(ctx context.Context, output *computationOutput) (interface{}, error) {
+func (e *execution) resolveAndExecuteFunction(ctx context.Context, output *computationOutput, p *path) (interface{}, error) {
 	value, err := output.Field.LazyResolver(ctx, output.Function)
 	if err != nil {
 		return nil, err
 	}
 
-	return e.execute(ctx, output.Field.Type, value, output.Selection.SelectionSet)
+	return e.execute(ctx, output.Field.Type, value, output.Selection.SelectionSet, p)
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"go.appointy.com/jaal/graphql"
@@ -283,3 +285,218 @@ func TestErrorCases(t *testing.T) {
 		t.Errorf("err, received %s", err)
 	}
 }
+
+func TestConcurrentExecution(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	var order []string
+
+	track := func(name string) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		order = append(order, name)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("slow", func(args struct{ Id int64 }) int64 {
+		track("slow")
+		return args.Id
+	})
+	query.FieldFunc("slowList", func() []int64 {
+		return []int64{1, 2, 3, 4}
+	})
+	query.FieldFunc("failing", func() (int64, error) {
+		return 0, errors.New("failing")
+	})
+
+	mutation := schema.Mutation()
+	mutation.FieldFunc("first", func() int64 {
+		track("first")
+		return 1
+	})
+	mutation.FieldFunc("second", func() int64 {
+		track("second")
+		return 2
+	})
+
+	builtSchema := schema.MustBuild()
+
+	execute := func(typ graphql.Type, queryString string) (interface{}, error) {
+		q, err := graphql.Parse(queryString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := graphql.ValidateQuery(context.Background(), typ, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+
+		e := graphql.Executor{MaxConcurrency: 4}
+		return e.Execute(context.Background(), typ, nil, q)
+	}
+
+	t.Run("sibling fields", func(t *testing.T) {
+		maxRunning = 0
+		result, err := execute(builtSchema.Query, `{
+			a: slow(id: 1)
+			b: slow(id: 2)
+			c: slow(id: 3)
+			d: slow(id: 4)
+			e: slow(id: 5)
+			f: slow(id: 6)
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(internal.AsJSON(result), internal.ParseJSON(`{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6}`)) {
+			t.Error("bad value", spew.Sdump(internal.AsJSON(result)))
+		}
+		if maxRunning < 2 || maxRunning > 4 {
+			t.Errorf("expected between 2 and 4 resolvers to run in parallel, but %d did", maxRunning)
+		}
+	})
+
+	t.Run("deterministic errors", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			_, err := execute(builtSchema.Query, `{
+				x: failing
+				y: failing
+				z: slowList
+			}`)
+			if !reflect.DeepEqual(err, &jerrors.Error{Message: "failing", Paths: []string{"x"}, Extensions: &jerrors.Extension{Code: codes.Unknown.String()}}) {
+				t.Fatalf("expected error on the first selection, received %v", err)
+			}
+		}
+	})
+
+	t.Run("serial mutation", func(t *testing.T) {
+		maxRunning = 0
+		order = nil
+		result, err := execute(builtSchema.Mutation, `mutation {
+			second
+			first
+			again: second
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(internal.AsJSON(result), internal.ParseJSON(`{"first": 1, "second": 2, "again": 2}`)) {
+			t.Error("bad value", spew.Sdump(internal.AsJSON(result)))
+		}
+		if maxRunning != 1 {
+			t.Errorf("expected mutation fields to run serially, but %d ran in parallel", maxRunning)
+		}
+		if !reflect.DeepEqual(order, []string{"second", "first", "second"}) {
+			t.Errorf("expected mutation fields to run in order, but ran %v", order)
+		}
+	})
+}
+
+func TestPartialResults(t *testing.T) {
+	type object struct {
+		Value int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("ok", func() string { return "ok" })
+	query.FieldFunc("nullable", func() (*string, error) {
+		return nil, errors.New("nullable failed")
+	})
+	query.FieldFunc("parent", func() *object {
+		return &object{}
+	})
+	query.FieldFunc("objects", func() *object {
+		return &object{}
+	})
+	query.FieldFunc("required", func() (string, error) {
+		return "", errors.New("required failed")
+	})
+
+	obj := schema.Object("Object", object{})
+	obj.FieldFunc("nonNull", func() (int64, error) {
+		return 0, errors.New("non null failed")
+	})
+	obj.FieldFunc("list", func() []object {
+		return []object{{Value: 0}, {Value: 1}, {Value: 2}}
+	})
+	obj.FieldFunc("odd", func(in object) (*int64, error) {
+		if in.Value%2 == 0 {
+			return nil, errors.New("even")
+		}
+		return &in.Value, nil
+	})
+
+	builtSchema := schema.MustBuild()
+
+	execute := func(queryString string) (interface{}, error) {
+		q, err := graphql.Parse(queryString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+
+		e := graphql.Executor{PartialResults: true, MaxConcurrency: 2}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	newError := func(message string, paths ...string) *jerrors.Error {
+		return &jerrors.Error{Message: message, Paths: paths, Extensions: &jerrors.Extension{Code: codes.Unknown.String()}}
+	}
+
+	t.Run("nullable and bubbling errors", func(t *testing.T) {
+		result, err := execute(`{
+			ok
+			nullable
+			parent { nonNull }
+			objects { list { value: odd } }
+		}`)
+
+		if !reflect.DeepEqual(internal.AsJSON(result), internal.ParseJSON(`{
+			"ok": "ok",
+			"nullable": null,
+			"parent": null,
+			"objects": {"list": [{"value": null}, {"value": 1}, {"value": null}]}
+		}`)) {
+			t.Error("bad value", spew.Sdump(internal.AsJSON(result)))
+		}
+
+		expected := &jerrors.MultiError{Errors: []*jerrors.Error{
+			newError("nullable failed", "nullable"),
+			newError("even", "objects", "list", "0", "value"),
+			newError("even", "objects", "list", "2", "value"),
+			newError("non null failed", "parent", "nonNull"),
+		}}
+		if !reflect.DeepEqual(err, expected) {
+			t.Error("bad errors", spew.Sdump(err))
+		}
+	})
+
+	t.Run("error reaching the root", func(t *testing.T) {
+		result, err := execute(`{
+			ok
+			required
+		}`)
+
+		if result != nil {
+			t.Error("expected no data, received", spew.Sdump(result))
+		}
+		if !reflect.DeepEqual(err, &jerrors.MultiError{Errors: []*jerrors.Error{newError("required failed", "required")}}) {
+			t.Error("bad errors", spew.Sdump(err))
+		}
+	})
+}
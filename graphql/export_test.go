@@ -0,0 +1,9 @@
+package graphql
+
+// WithoutSource returns a copy of query without the source kept for Validate, so that parsed queries can be
+// compared with reflect.DeepEqual.
+func WithoutSource(query *Query) *Query {
+	q := *query
+	q.document, q.operation, q.variables = nil, nil, nil
+	return &q
+}
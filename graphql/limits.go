@@ -0,0 +1,151 @@
+package graphql
+
+// This file contains rules limiting the size of queries, so that expensive queries are rejected before they are
+// executed. The violations are reported with the ResourceExhausted code.
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"google.golang.org/grpc/codes"
+)
+
+// MaxDepth rejects operations nesting fields more than max levels deep. Root fields are at depth 1.
+func MaxDepth(max int) Rule {
+	return func(c *ValidationContext) {
+		m := &measurer{c: c, combine: math.Max, memo: make(map[string]float64)}
+		m.field = func(parent Type, field *ast.Field) float64 {
+			return 1 + m.measure(nil, field.SelectionSet)
+		}
+
+		if d := m.measure(nil, c.Operation.SelectionSet); d > float64(max) {
+			c.report(codes.ResourceExhausted, fmt.Sprintf("query has depth %.0f, which exceeds the maximum depth of %d", d, max), []ast.Node{c.Operation})
+		}
+	}
+}
+
+// MaxAliases rejects operations using more than max aliases, counting the aliases of fragments at every spread.
+func MaxAliases(max int) Rule {
+	return func(c *ValidationContext) {
+		m := &measurer{c: c, combine: add, memo: make(map[string]float64)}
+		m.field = func(parent Type, field *ast.Field) float64 {
+			n := m.measure(nil, field.SelectionSet)
+			if field.Alias != nil {
+				n++
+			}
+			return n
+		}
+
+		if n := m.measure(nil, c.Operation.SelectionSet); n > float64(max) {
+			c.report(codes.ResourceExhausted, fmt.Sprintf("query has %.0f aliases, which exceeds the maximum of %d", n, max), []ast.Node{c.Operation})
+		}
+	}
+}
+
+// MaxRootFields rejects operations selecting more than max root fields.
+func MaxRootFields(max int) Rule {
+	return func(c *ValidationContext) {
+		m := &measurer{c: c, combine: add, memo: make(map[string]float64)}
+		m.field = func(parent Type, field *ast.Field) float64 {
+			return 1
+		}
+
+		if n := m.measure(nil, c.Operation.SelectionSet); n > float64(max) {
+			c.report(codes.ResourceExhausted, fmt.Sprintf("query selects %.0f root fields, which exceeds the maximum of %d", n, max), []ast.Node{c.Operation})
+		}
+	}
+}
+
+// MaxComplexity rejects operations whose complexity exceeds max. The complexity of an operation is the sum of the
+// costs of its fields, where the complexity of the selections of a field is multiplied by the values of its cost
+// multipliers.
+func MaxComplexity(max int) Rule {
+	return func(c *ValidationContext) {
+		m := &measurer{c: c, combine: add, memo: make(map[string]float64)}
+		m.field = func(parent Type, field *ast.Field) float64 {
+			definition := fieldDefinition(parent, field.Name.Value)
+			if definition == nil {
+				return 0
+			}
+
+			multiplier := 1.0
+			for _, name := range definition.CostMultipliers {
+				multiplier *= c.argumentNumber(field, name)
+			}
+			return float64(definition.Cost) + multiplier*m.measure(namedType(definition.Type), field.SelectionSet)
+		}
+
+		if n := m.measure(c.RootType(), c.Operation.SelectionSet); n > float64(max) {
+			c.report(codes.ResourceExhausted, fmt.Sprintf("query has complexity %.0f, which exceeds the maximum complexity of %d", n, max), []ast.Node{c.Operation})
+		}
+	}
+}
+
+func add(a, b float64) float64 {
+	return a + b
+}
+
+// measurer combines the measures of the fields of a selection set, including the fields of its fragments. The
+// fragments are measured once, however many times they are spread.
+type measurer struct {
+	c       *ValidationContext
+	combine func(a, b float64) float64
+	field   func(parent Type, field *ast.Field) float64
+	memo    map[string]float64
+}
+
+func (m *measurer) measure(parent Type, selectionSet *ast.SelectionSet) float64 {
+	if selectionSet == nil {
+		return 0
+	}
+
+	total := 0.0
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			total = m.combine(total, m.field(parent, selection))
+
+		case *ast.InlineFragment:
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = m.c.Type(selection.TypeCondition.Name.Value)
+			}
+			total = m.combine(total, m.measure(typ, selection.SelectionSet))
+
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			n, ok := m.memo[name]
+			if !ok {
+				if fragment := m.c.Fragment(name); fragment != nil {
+					n = m.measure(m.c.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet)
+				}
+				m.memo[name] = n
+			}
+			total = m.combine(total, n)
+		}
+	}
+	return total
+}
+
+// argumentNumber returns the numeric value of an argument of field, or 1 if it is not a positive number.
+func (c *ValidationContext) argumentNumber(field *ast.Field, name string) float64 {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != name {
+			continue
+		}
+
+		var n float64
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			n, _ = strconv.ParseFloat(value.Value, 64)
+		case *ast.Variable:
+			n, _ = c.Variables[value.Name.Value].(float64)
+		}
+		if n > 0 && !math.IsInf(n, 0) {
+			return n
+		}
+	}
+	return 1
+}
@@ -0,0 +1,95 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+func TestLimits(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(u *User) string {
+		return u.Name
+	})
+	user.FieldFunc("friends", func(u *User, args struct{ First int64 }) []*User {
+		return nil
+	}, schemabuilder.Cost(5), schemabuilder.CostMultipliers("first"))
+
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return &User{}
+	})
+	builtSchema := schema.MustBuild()
+
+	cases := []struct {
+		name   string
+		rule   graphql.Rule
+		query  string
+		vars   map[string]interface{}
+		errors []string
+	}{
+		{
+			name:  "depth within limit",
+			rule:  graphql.MaxDepth(3),
+			query: `{ me { friends(first: 1) { name } } }`,
+		},
+		{
+			name:   "depth over limit",
+			rule:   graphql.MaxDepth(3),
+			query:  `{ me { ...f } } fragment f on User { friends(first: 1) { friends(first: 1) { name } } }`,
+			errors: []string{"query has depth 4, which exceeds the maximum depth of 3"},
+		},
+		{
+			name:   "aliases over limit",
+			rule:   graphql.MaxAliases(2),
+			query:  `{ a: me { ...f } b: me { ...f } } fragment f on User { n: name }`,
+			errors: []string{"query has 4 aliases, which exceeds the maximum of 2"},
+		},
+		{
+			name:   "root fields over limit",
+			rule:   graphql.MaxRootFields(1),
+			query:  `{ a: me { name } ... on Query { b: me { name } } }`,
+			errors: []string{"query selects 2 root fields, which exceeds the maximum of 1"},
+		},
+		{
+			// me (1) + friends (5) + 10 * (name (1) + friends (5) + 10 * name (1)) = 166
+			name:   "complexity over limit",
+			rule:   graphql.MaxComplexity(165),
+			query:  `query($n: Int) { me { friends(first: $n) { name friends(first: 10) { name } } } }`,
+			vars:   map[string]interface{}{"n": float64(10)},
+			errors: []string{"query has complexity 166, which exceeds the maximum complexity of 165"},
+		},
+		{
+			name:  "complexity within limit",
+			rule:  graphql.MaxComplexity(166),
+			query: `query($n: Int) { me { friends(first: $n) { name friends(first: 10) { name } } } }`,
+			vars:  map[string]interface{}{"n": float64(10)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := graphql.Parse(c.query, c.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var messages []string
+			if err := graphql.Validate(builtSchema, query, c.rule); err != nil {
+				for _, e := range err.(*jerrors.MultiError).Errors {
+					assert.Equal(t, "ResourceExhausted", e.Extensions.Code)
+					messages = append(messages, e.Message)
+				}
+			}
+			assert.Equal(t, c.errors, messages)
+		})
+	}
+}
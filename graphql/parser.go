@@ -17,19 +17,53 @@ type Query struct {
 	Name string
 	Kind string
 	*SelectionSet
+
+	// Variables are the variables declared by the operation.
+	Variables []*VariableDefinition
+
+	// document, operation and variables are kept for Validate, which reports locations in the source.
+	document  *ast.Document
+	operation *ast.OperationDefinition
+	variables map[string]interface{}
 }
 
 // Parse parses an input GraphQL string into a *Query
 //
 // Parse validates that the query looks syntactically correct and contains no cycles or unused fragments or immediate conflicts.
 // However, it does not validate that the query is legal under a given schema, which instead is done by ValidateQuery.
-func Parse(source string, vars map[string]interface{}) (*Query, error) {
+//
+// The source may contain many operations, in which case the operation named by the optional operationName is
+// returned. Without an operationName, the source must contain a single operation.
+func Parse(source string, vars map[string]interface{}, operationName ...string) (*Query, error) {
+	document, err := ParseDocument(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var name string
+	if len(operationName) > 0 {
+		name = operationName[0]
+	}
+	return document.Operation(name, vars)
+}
+
+// Document is a parsed GraphQL source. It is not modified by Operation, so a Document can be cached and shared
+// by the requests executing its operations, which then skip parsing the source.
+type Document struct {
+	document *ast.Document
+
+	operationDefinitions []*ast.OperationDefinition
+	fragmentDefinitions  map[string]*ast.FragmentDefinition
+}
+
+// ParseDocument parses an input GraphQL string, whose operations are then bound to variables with Operation.
+func ParseDocument(source string) (*Document, error) {
 	document, err := parser.Parse(parser.ParseParams{Source: source})
 	if err != nil {
 		return nil, err
 	}
 
-	var queryDefinition *ast.OperationDefinition
+	var operationDefinitions []*ast.OperationDefinition
 	fragmentDefinitions := make(map[string]*ast.FragmentDefinition)
 
 	for _, definition := range document.Definitions {
@@ -45,20 +79,59 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 			if definition.Operation != "query" && definition.Operation != "mutation" && definition.Operation != "subscription" {
 				return nil, fmt.Errorf("only supports queries, mutations and subscriptions")
 			}
-			if queryDefinition != nil {
-				return nil, fmt.Errorf("only support a single query")
-			}
-			queryDefinition = definition
+			operationDefinitions = append(operationDefinitions, definition)
 
 		default:
 			return nil, fmt.Errorf("unsupported definition")
 		}
 	}
 
-	if queryDefinition == nil {
+	if len(operationDefinitions) == 0 {
 		return nil, fmt.Errorf("must have a single query")
 	}
 
+	return &Document{
+		document:             document,
+		operationDefinitions: operationDefinitions,
+		fragmentDefinitions:  fragmentDefinitions,
+	}, nil
+}
+
+// OperationNames returns the names of the operations of the document. The name of an anonymous operation is
+// empty.
+func (d *Document) OperationNames() []string {
+	names := make([]string, 0, len(d.operationDefinitions))
+	for _, definition := range d.operationDefinitions {
+		var name string
+		if definition.Name != nil {
+			name = definition.Name.Value
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Operation returns the operation named operationName as a *Query, with its arguments bound to vars. An empty
+// operationName selects the only operation of the document.
+func (d *Document) Operation(operationName string, vars map[string]interface{}) (*Query, error) {
+	query, err := d.Prepare(operationName)
+	if err != nil {
+		return query, err
+	}
+	return query.Bind(vars), nil
+}
+
+// Prepare returns the operation named operationName like Operation, but without binding its variables. The
+// returned query must be bound with Bind before it is validated with ValidateQuery and executed. It is never
+// modified, so it can be cached and bound concurrently.
+func (d *Document) Prepare(operationName string) (*Query, error) {
+	document, operationDefinitions, fragmentDefinitions := d.document, d.operationDefinitions, d.fragmentDefinitions
+
+	queryDefinition, err := selectOperation(operationDefinitions, operationName)
+	if err != nil {
+		return nil, err
+	}
+
 	kind := queryDefinition.Operation
 	var name string
 	if queryDefinition.Name != nil {
@@ -69,12 +142,18 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 		Name:         name,
 		Kind:         kind,
 		SelectionSet: nil,
+		document:     document,
+		operation:    queryDefinition,
 	}
 
 	// Parse variable definitions, default values, etc.
-	var defaultedVars map[string]interface{}
 	for _, variableDefinition := range queryDefinition.VariableDefinitions {
 		name := variableDefinition.Variable.Name.Value
+		definition := &VariableDefinition{
+			Name: name,
+			Type: typeRefFromAST(variableDefinition.Type),
+		}
+		rv.Variables = append(rv.Variables, definition)
 
 		if _, ok := variableDefinition.Type.(*ast.NonNull); ok {
 			if variableDefinition.DefaultValue != nil {
@@ -85,32 +164,14 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 		}
 
 		if variableDefinition.DefaultValue != nil {
-			// Ignore default if the value exists.
-			if vars[name] != nil {
-				continue
-			}
-
-			// Lazily initialize defaultedVars if needed.
-			if defaultedVars == nil {
-				defaultedVars = make(map[string]interface{})
-				for k, v := range vars {
-					defaultedVars[k] = v
-				}
-			}
-
-			val, err := valueToJson(variableDefinition.DefaultValue, nil)
+			val, err := valueToJson(variableDefinition.DefaultValue)
 			if err != nil {
 				return rv, fmt.Errorf("failed to parse default value: %s", err.Error())
 			}
-
-			defaultedVars[name] = val
+			definition.DefaultValue = val
 		}
 	}
 
-	if defaultedVars != nil {
-		vars = defaultedVars
-	}
-
 	globalFragments := make(map[string]*FragmentDefinition)
 	for name, fragment := range fragmentDefinitions {
 		globalFragments[name] = &FragmentDefinition{
@@ -120,19 +181,28 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	}
 
 	for name, fragment := range fragmentDefinitions {
-		selectionSet, err := parseSelectionSet(fragment.SelectionSet, globalFragments, vars)
+		selectionSet, err := parseSelectionSet(fragment.SelectionSet, globalFragments)
 		if err != nil {
 			return rv, err
 		}
 		globalFragments[name].SelectionSet = selectionSet
 	}
 
-	selectionSet, err := parseSelectionSet(queryDefinition.SelectionSet, globalFragments, vars)
-	if err != nil {
-		return rv, err
+	// Fragments used by any of the operations are not unused, so the other operations are parsed as well.
+	var selectionSets []*SelectionSet
+	var selectionSet *SelectionSet
+	for _, definition := range operationDefinitions {
+		parsed, err := parseSelectionSet(definition.SelectionSet, globalFragments)
+		if err != nil {
+			return rv, err
+		}
+		if definition == queryDefinition {
+			selectionSet = parsed
+		}
+		selectionSets = append(selectionSets, parsed)
 	}
 
-	if err := detectCyclesAndUnusedFragments(selectionSet, globalFragments); err != nil {
+	if err := detectCyclesAndUnusedFragments(selectionSets, globalFragments); err != nil {
 		return rv, err
 	}
 
@@ -145,8 +215,42 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	return rv, nil
 }
 
-// valueToJson takes a graphql-go ast value and converts it to a value like those generated by json.Unmarshal
-func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, error) {
+// selectOperation returns the operation named operationName, or the only operation if operationName is empty.
+func selectOperation(definitions []*ast.OperationDefinition, operationName string) (*ast.OperationDefinition, error) {
+	names := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		if definition.Name == nil {
+			if len(definitions) > 1 {
+				return nil, fmt.Errorf("anonymous operation must be the only defined operation")
+			}
+			continue
+		}
+
+		if names[definition.Name.Value] {
+			return nil, fmt.Errorf(`duplicate operation named "%s"`, definition.Name.Value)
+		}
+		names[definition.Name.Value] = true
+	}
+
+	if operationName == "" {
+		if len(definitions) > 1 {
+			return nil, fmt.Errorf("must provide an operation name if the query contains multiple operations")
+		}
+		return definitions[0], nil
+	}
+
+	for _, definition := range definitions {
+		if definition.Name != nil && definition.Name.Value == operationName {
+			return definition, nil
+		}
+	}
+
+	return nil, fmt.Errorf(`unknown operation named "%s"`, operationName)
+}
+
+// valueToJson takes a graphql-go ast value and converts it to a value like those generated by json.Unmarshal. The
+// variables are converted to placeholders, which are replaced by their values when the query is bound.
+func valueToJson(value ast.Value) (interface{}, error) {
 	switch value := value.(type) {
 	case *ast.IntValue:
 		v, err := strconv.ParseInt(value.Value, 10, 64)
@@ -168,11 +272,7 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 	case *ast.EnumValue:
 		return value.Value, nil
 	case *ast.Variable:
-		actual, ok := vars[value.Name.Value]
-		if !ok {
-			return nil, nil
-		}
-		return actual, nil
+		return variable(value.Name.Value), nil
 	case *ast.ObjectValue:
 		obj := make(map[string]interface{})
 		for _, field := range value.Fields {
@@ -180,7 +280,7 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 			if _, found := obj[name]; found {
 				return nil, fmt.Errorf("duplicate field")
 			}
-			value, err := valueToJson(field.Value, vars)
+			value, err := valueToJson(field.Value)
 			if err != nil {
 				return nil, err
 			}
@@ -190,7 +290,7 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 	case *ast.ListValue:
 		list := make([]interface{}, 0, len(value.Values))
 		for _, item := range value.Values {
-			value, err := valueToJson(item, vars)
+			value, err := valueToJson(item)
 			if err != nil {
 				return nil, err
 			}
@@ -202,8 +302,8 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 	}
 }
 
-// parseSelectionSet takes a grapqhl-go selection set and converts it to a simplified *SelectionSet, bindings vars
-func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*FragmentDefinition, vars map[string]interface{}) (*SelectionSet, error) {
+// parseSelectionSet takes a grapqhl-go selection set and converts it to a simplified *SelectionSet
+func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*FragmentDefinition) (*SelectionSet, error) {
 	if input == nil {
 		return nil, nil
 	}
@@ -218,17 +318,17 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				alias = selection.Alias.Value
 			}
 
-			args, err := argsToJson(selection.Arguments, vars)
+			args, err := argsToJson(selection.Arguments)
 			if err != nil {
 				return nil, err
 			}
 
-			directives, err := parseDirectives(selection.Directives, vars)
+			directives, err := parseDirectives(selection.Directives)
 			if err != nil {
 				return nil, err
 			}
 
-			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars)
+			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments)
 			if err != nil {
 				return nil, err
 			}
@@ -249,7 +349,7 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				return nil, fmt.Errorf("unknown fragment")
 			}
 
-			directives, err := parseDirectives(selection.Directives, vars)
+			directives, err := parseDirectives(selection.Directives)
 			if err != nil {
 				return nil, err
 			}
@@ -264,12 +364,12 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 		case *ast.InlineFragment:
 			on := selection.TypeCondition.Name.Value
 
-			directives, err := parseDirectives(selection.Directives, vars)
+			directives, err := parseDirectives(selection.Directives)
 			if err != nil {
 				return nil, err
 			}
 
-			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars)
+			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments)
 			if err != nil {
 				return nil, err
 			}
@@ -292,14 +392,14 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 }
 
 // argsToJson converts a graphql-go ast argument list to a json.Marshal-style map[string]interface{}
-func argsToJson(input []*ast.Argument, vars map[string]interface{}) (interface{}, error) {
+func argsToJson(input []*ast.Argument) (interface{}, error) {
 	args := make(map[string]interface{})
 	for _, arg := range input {
 		name := arg.Name.Value
 		if _, found := args[name]; found {
 			return nil, fmt.Errorf("duplicate arg")
 		}
-		value, err := valueToJson(arg.Value, vars)
+		value, err := valueToJson(arg.Value)
 		if err != nil {
 			return nil, err
 		}
@@ -316,10 +416,10 @@ const (
 	visited
 )
 
-func parseDirectives(directives []*ast.Directive, vars map[string]interface{}) ([]*Directive, error) {
+func parseDirectives(directives []*ast.Directive) ([]*Directive, error) {
 	d := make([]*Directive, 0, len(directives))
 	for _, directive := range directives {
-		args, err := argsToJson(directive.Arguments, vars)
+		args, err := argsToJson(directive.Arguments)
 		if err != nil {
 			return nil, err
 		}
@@ -332,8 +432,9 @@ func parseDirectives(directives []*ast.Directive, vars map[string]interface{}) (
 	return d, nil
 }
 
-// detectCyclesAndUnusedFragments finds cycles in fragments that include eachother as well as fragments that don't appear anywhere
-func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments map[string]*FragmentDefinition) error {
+// detectCyclesAndUnusedFragments finds cycles in fragments that include eachother as well as fragments that don't appear in any
+// of the selectionSets
+func detectCyclesAndUnusedFragments(selectionSets []*SelectionSet, globalFragments map[string]*FragmentDefinition) error {
 	state := make(map[*FragmentDefinition]visitState)
 
 	var visitFragment func(spread *FragmentSpread) error
@@ -376,8 +477,10 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 		return nil
 	}
 
-	if err := visitSelectionSet(selectionSet); err != nil {
-		return err
+	for _, selectionSet := range selectionSets {
+		if err := visitSelectionSet(selectionSet); err != nil {
+			return err
+		}
 	}
 
 	for _, fragment := range globalFragments {
@@ -461,9 +564,11 @@ func detectConflicts(selectionSet *SelectionSet) error {
 //     groups: { name name id { widgets { name } } }
 //
 // Flatten does _not_ flatten out the inner queries, so the name above does not
-// get flattened out yet.
+// get flattened out yet. The selections are returned in the order in which
+// their aliases first appear in the query.
 func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 	grouped := make(map[string][]*Selection)
+	var aliases []string // aliases in the order of their first appearance
 
 	state := make(map[*SelectionSet]visitState)
 	var visit func(*SelectionSet) error
@@ -473,6 +578,9 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 		}
 
 		for _, selection := range selectionSet.Selections {
+			if _, ok := grouped[selection.Alias]; !ok {
+				aliases = append(aliases, selection.Alias)
+			}
 			grouped[selection.Alias] = append(grouped[selection.Alias], selection)
 		}
 		for _, fragment := range selectionSet.Fragments {
@@ -497,7 +605,8 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 	}
 
 	var flattened []*Selection
-	for _, selections := range grouped {
+	for _, alias := range aliases {
+		selections := grouped[alias]
 		if len(selections) == 1 || selections[0].SelectionSet == nil {
 			flattened = append(flattened, selections[0])
 			continue
@@ -514,6 +623,7 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 			Alias:        selections[0].Alias,
 			Args:         selections[0].Args,
 			SelectionSet: merged,
+			UseBatch:     selections[0].UseBatch,
 		})
 	}
 
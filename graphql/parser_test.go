@@ -1,8 +1,10 @@
 package graphql_test
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
+	"sync"
 	"testing"
 
 	. "go.appointy.com/jaal/graphql"
@@ -176,8 +178,12 @@ mutation foo($var: bar) {
 				},
 			},
 		},
+		Variables: []*VariableDefinition{
+			{Name: "var", Type: &TypeRef{Name: "bar"}},
+		},
 	}
-	if !reflect.DeepEqual(query, expected) {
+
+	if !reflect.DeepEqual(WithoutSource(query), expected) {
 		t.Error("unexpected parse")
 	}
 }
@@ -196,8 +202,8 @@ func TestParseUnsupported(t *testing.T) {
 {
 	baz
 }`, map[string]interface{}{})
-	if err == nil || err.Error() != "only support a single query" {
-		t.Error("expected multiple queries to fail", err)
+	if err == nil || err.Error() != "anonymous operation must be the only defined operation" {
+		t.Error("expected multiple anonymous queries to fail", err)
 	}
 
 	_, err = Parse(`
@@ -306,3 +312,119 @@ func TestSkippedFragment(t *testing.T) {
 		t.Errorf("expected no error, received %s", err.Error())
 	}
 }
+
+func TestParseOperationName(t *testing.T) {
+	const source = `
+query A {
+	...frag
+}
+
+query B($x: Int) {
+	b(x: $x)
+}
+
+mutation C {
+	c
+}
+
+fragment frag on Query {
+	a
+}`
+
+	query, err := Parse(source, map[string]interface{}{"x": float64(1)}, "B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Name != "B" || query.Kind != "query" || len(query.Selections) != 1 || query.Selections[0].Name != "b" {
+		t.Error("unexpected parse", query)
+	}
+	if !reflect.DeepEqual(query.Selections[0].Args, map[string]interface{}{"x": float64(1)}) {
+		t.Error("unexpected args", query.Selections[0].Args)
+	}
+
+	query, err = Parse(source, nil, "C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Name != "C" || query.Kind != "mutation" {
+		t.Error("unexpected parse", query)
+	}
+
+	if _, err := Parse(source, nil); err == nil || err.Error() != "must provide an operation name if the query contains multiple operations" {
+		t.Error("expected missing operation name to fail", err)
+	}
+
+	if _, err := Parse(source, nil, "D"); err == nil || err.Error() != `unknown operation named "D"` {
+		t.Error("expected unknown operation to fail", err)
+	}
+
+	if _, err := Parse(`query A { a } query A { b }`, nil, "A"); err == nil || err.Error() != `duplicate operation named "A"` {
+		t.Error("expected duplicate operations to fail", err)
+	}
+
+	if _, err := Parse(`query A { a } query B { b } fragment frag on Query { c }`, nil, "A"); err == nil || err.Error() != "unused fragment" {
+		t.Error("expected unused fragment to fail", err)
+	}
+}
+
+func TestPrepareBind(t *testing.T) {
+	document, err := ParseDocument(`
+query A($x: Int = 2, $skip: Boolean) {
+	b(x: $x, list: [$x, 3]) @skip(if: $skip) {
+		...frag
+	}
+}
+
+fragment frag on B {
+	c(x: $x)
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prepared, err := document.Prepare("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := &Object{Name: "Query", Fields: map[string]*Field{
+		"b": {
+			Type: &Object{Name: "B", Fields: map[string]*Field{
+				"c": {Type: &Scalar{Type: "Int"}, ParseArguments: func(json interface{}) (interface{}, error) { return json, nil }},
+			}},
+			ParseArguments: func(json interface{}) (interface{}, error) { return json, nil },
+		},
+	}}
+
+	// Binding copies the prepared query, which can be bound concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(x float64) {
+			defer wg.Done()
+
+			query := prepared.Bind(map[string]interface{}{"x": x, "skip": true})
+			if err := ValidateQuery(context.Background(), schema, query.SelectionSet); err != nil {
+				t.Error(err)
+			}
+
+			b := query.Selections[0]
+			if !reflect.DeepEqual(b.Args, map[string]interface{}{"x": x, "list": []interface{}{x, float64(3)}}) {
+				t.Error("unexpected args", b.Args)
+			}
+			if !reflect.DeepEqual(b.Directives[0].Args, map[string]interface{}{"if": true}) {
+				t.Error("unexpected directive args", b.Directives[0].Args)
+			}
+			if c := b.SelectionSet.Fragments[0].Fragment.SelectionSet.Selections[0]; !reflect.DeepEqual(c.Args, map[string]interface{}{"x": x}) {
+				t.Error("unexpected fragment args", c.Args)
+			}
+		}(float64(i))
+	}
+	wg.Wait()
+
+	// The default values are bound to the variables which are not provided.
+	query := prepared.Bind(nil)
+	if !reflect.DeepEqual(query.Selections[0].Args, map[string]interface{}{"x": float64(2), "list": []interface{}{float64(2), float64(3)}}) {
+		t.Error("unexpected args", query.Selections[0].Args)
+	}
+}
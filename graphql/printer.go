@@ -0,0 +1,297 @@
+package graphql
+
+// This file contains PrintSchema, which prints a schema in the GraphQL schema definition language (SDL).
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PrintSchema prints the schema in the GraphQL schema definition language.
+//
+// Types, fields, arguments and enum values are sorted by name, so that the output is deterministic. The builtin
+// scalars, the introspection types and the root types without fields are not printed.
+func PrintSchema(schema *Schema) string {
+	roots := []struct {
+		operation string
+		name      string
+		typ       Type
+	}{
+		{"query", "Query", namedType(schema.Query)},
+		{"mutation", "Mutation", namedType(schema.Mutation)},
+		{"subscription", "Subscription", namedType(schema.Subscription)},
+	}
+
+	skipped := make(map[string]bool)
+	for name := range builtinScalars {
+		skipped[name] = true
+	}
+
+	var definitions []string
+	var operations []string
+	conventional := true
+	for _, root := range roots {
+		if root.typ == nil {
+			continue
+		}
+		if object, ok := root.typ.(*Object); ok && len(visibleFields(object.Fields)) == 0 {
+			skipped[object.Name] = true
+			continue
+		}
+
+		operations = append(operations, fmt.Sprintf("  %s: %s", root.operation, root.typ))
+		if root.typ.String() != root.name {
+			conventional = false
+		}
+	}
+	if !conventional {
+		definitions = append(definitions, fmt.Sprintf("schema {\n%s\n}", strings.Join(operations, "\n")))
+	}
+
+	types := schema.namedTypes()
+	names := make([]string, 0, len(types))
+	for name := range types {
+		if !skipped[name] && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		definitions = append(definitions, printType(types[name]))
+	}
+
+	return strings.Join(definitions, "\n\n") + "\n"
+}
+
+func printType(typ Type) string {
+	switch typ := typ.(type) {
+	case *Scalar:
+		return fmt.Sprintf("scalar %s", typ.Type)
+
+	case *Enum:
+		values := append([]string{}, typ.Values...)
+		sort.Strings(values)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "enum %s {\n", typ.Type)
+		for _, value := range values {
+			b.WriteString(printDescription(typ.ValueDescriptions[value], "  "))
+			fmt.Fprintf(&b, "  %s%s\n", value, printDeprecated(typ.ValueDeprecationReasons[value]))
+		}
+		b.WriteString("}")
+		return b.String()
+
+	case *Object:
+		var b strings.Builder
+		b.WriteString(printDescription(typ.Description, ""))
+		fmt.Fprintf(&b, "type %s", typ.Name)
+		if len(typ.Interfaces) > 0 {
+			interfaces := make([]string, 0, len(typ.Interfaces))
+			for name := range typ.Interfaces {
+				interfaces = append(interfaces, name)
+			}
+			sort.Strings(interfaces)
+			fmt.Fprintf(&b, " implements %s", strings.Join(interfaces, " & "))
+		}
+		b.WriteString(printFields(typ.Fields))
+		return b.String()
+
+	case *Interface:
+		var b strings.Builder
+		b.WriteString(printDescription(typ.Description, ""))
+		fmt.Fprintf(&b, "interface %s", typ.Name)
+		b.WriteString(printFields(typ.Fields))
+		return b.String()
+
+	case *Union:
+		members := make([]string, 0, len(typ.Types))
+		for name := range typ.Types {
+			members = append(members, name)
+		}
+		sort.Strings(members)
+
+		if len(members) == 0 {
+			return fmt.Sprintf("%sunion %s", printDescription(typ.Description, ""), typ.Name)
+		}
+		return fmt.Sprintf("%sunion %s = %s", printDescription(typ.Description, ""), typ.Name, strings.Join(members, " | "))
+
+	case *InputObject:
+		names := make([]string, 0, len(typ.InputFields))
+		for name := range typ.InputFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "input %s {\n", typ.Name)
+		for _, name := range names {
+			b.WriteString(printDescription(typ.FieldDescriptions[name], "  "))
+			fmt.Fprintf(&b, "  %s: %s\n", name, typ.InputFields[name])
+		}
+		b.WriteString("}")
+		return b.String()
+
+	default:
+		return ""
+	}
+}
+
+// visibleFields returns the sorted names of the fields, without the introspection fields.
+func visibleFields(fields map[string]*Field) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printFields(fields map[string]*Field) string {
+	names := visibleFields(fields)
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(" {\n")
+	for _, name := range names {
+		field := fields[name]
+		b.WriteString(printDescription(field.Description, "  "))
+		fmt.Fprintf(&b, "  %s%s: %s%s\n", name, printArgs(field), field.Type, printDeprecated(field.DeprecationReason))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func printArgs(field *Field) string {
+	if len(field.Args) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(field.Args))
+	described := false
+	for name := range field.Args {
+		names = append(names, name)
+		if field.ArgDescriptions[name] != "" {
+			described = true
+		}
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("%s: %s", name, field.Args[name]))
+	}
+	if !described {
+		return "(" + strings.Join(args, ", ") + ")"
+	}
+
+	// Arguments with descriptions are printed on their own lines.
+	var b strings.Builder
+	b.WriteString("(\n")
+	for i, name := range names {
+		b.WriteString(printDescription(field.ArgDescriptions[name], "    "))
+		fmt.Fprintf(&b, "    %s\n", args[i])
+	}
+	b.WriteString("  )")
+	return b.String()
+}
+
+func printDeprecated(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case DefaultDeprecationReason:
+		return " @deprecated"
+	default:
+		return fmt.Sprintf(" @deprecated(reason: %s)", quoteString(reason))
+	}
+}
+
+// printDescription prints a description on the line before a definition, as a block string if it spans many lines
+// and reads the same once parsed.
+func printDescription(description, indent string) string {
+	if description == "" {
+		return ""
+	}
+	if !strings.Contains(description, "\n") || !isBlockString(description) {
+		return fmt.Sprintf("%s%s\n", indent, quoteString(description))
+	}
+
+	lines := strings.Split(strings.Replace(description, `"""`, `\"""`, -1), "\n")
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\"\"\"\n", indent)
+	for _, line := range lines {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(&b, "%s\"\"\"\n", indent)
+	return b.String()
+}
+
+// quoteString prints s as a GraphQL string, which escapes the quotes, the backslashes and the control characters.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isBlockString checks if s is kept as is by a block string, whose value has no control characters but tabs
+// and line breaks, and is stripped of its blank first and last lines and of the indentation common to its lines.
+func isBlockString(s string) bool {
+	for _, r := range s {
+		if (r < 0x20 && r != '\t' && r != '\n') || r == 0x7f {
+			return false
+		}
+	}
+
+	lines := strings.Split(s, "\n")
+	if strings.TrimSpace(lines[0]) == "" || strings.TrimSpace(lines[len(lines)-1]) == "" {
+		return false
+	}
+	indented := true
+	for _, line := range lines {
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		if trimmed == "" && line != "" {
+			// A line of spaces is emptied.
+			return false
+		}
+		if trimmed != "" && trimmed == line {
+			indented = false
+		}
+	}
+	return !indented
+}
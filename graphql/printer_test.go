@@ -0,0 +1,175 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+type printerRole int32
+
+type PrinterUser struct {
+	Id   string
+	Name string
+}
+
+type PrinterBot struct {
+	Id    string
+	Owner string
+}
+
+type PrinterActor struct {
+	schemabuilder.Interface
+	*PrinterUser
+	*PrinterBot
+}
+
+type PrinterResult struct {
+	schemabuilder.Union
+	*PrinterUser
+	*PrinterBot
+}
+
+type PrinterFilter struct {
+	Role  printerRole
+	Since *schemabuilder.Timestamp
+}
+
+func TestPrintSchema(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Enum(printerRole(0), map[string]printerRole{
+		"ADMIN":  0,
+		"MEMBER": 1,
+		"GUEST":  2,
+	},
+		schemabuilder.EnumValueDescription("ADMIN", "Can manage the organization."),
+		schemabuilder.EnumValueDeprecated("GUEST", "Use MEMBER instead."),
+	)
+
+	user := schema.Object("User", PrinterUser{})
+	user.FieldFunc("id", func(in PrinterUser) string { return in.Id })
+	user.FieldFunc("name", func(in PrinterUser) string { return in.Name }, schemabuilder.Description("The full name.\nIt may be empty."))
+
+	bot := schema.Object("Bot", PrinterBot{})
+	bot.FieldFunc("id", func(in PrinterBot) string { return in.Id })
+	bot.FieldFunc("owner", func(in PrinterBot) string { return in.Owner }, schemabuilder.Deprecated(""))
+
+	filter := schema.InputObject("Filter", PrinterFilter{})
+	filter.FieldFunc("role", func(target *PrinterFilter, source printerRole) { target.Role = source })
+	filter.FieldFunc("since", func(target *PrinterFilter, source *schemabuilder.Timestamp) { target.Since = source })
+
+	query := schema.Query()
+	query.FieldFunc("actor", func(ctx context.Context, args struct{ Id string }) *PrinterActor { return nil })
+	query.FieldFunc("search", func(ctx context.Context, args struct {
+		Filter *PrinterFilter
+		First  int32 `description:"The number of results."`
+	}) []*PrinterResult {
+		return nil
+	}, schemabuilder.Description("Searches the actors."))
+	query.FieldFunc("now", func() schemabuilder.Timestamp { return schemabuilder.Timestamp{} })
+
+	built := schema.MustBuild()
+
+	assert.Equal(t, `type Bot implements PrinterActor {
+  id: String!
+  owner: String! @deprecated
+}
+
+input Filter {
+  role: printerRole
+  since: Timestamp
+}
+
+interface PrinterActor {
+  id: String!
+}
+
+union PrinterResult = Bot | User
+
+type Query {
+  actor(id: String): PrinterActor
+  now: Timestamp!
+  "Searches the actors."
+  search(
+    filter: Filter
+    "The number of results."
+    first: Int
+  ): [PrinterResult!]!
+}
+
+scalar Timestamp
+
+type User implements PrinterActor {
+  id: String!
+  """
+  The full name.
+  It may be empty.
+  """
+  name: String!
+}
+
+enum printerRole {
+  "Can manage the organization."
+  ADMIN
+  GUEST @deprecated(reason: "Use MEMBER instead.")
+  MEMBER
+}
+`, graphql.PrintSchema(built))
+
+	// The output is deterministic.
+	assert.Equal(t, graphql.PrintSchema(built), graphql.PrintSchema(built))
+}
+
+func TestPrintSchemaRoots(t *testing.T) {
+	schema := &graphql.Schema{
+		Query: &graphql.Object{Name: "RootQuery", Fields: map[string]*graphql.Field{
+			"version": {Type: &graphql.NonNull{Type: &graphql.Scalar{Type: "String"}}},
+		}},
+		Mutation: &graphql.Object{Name: "RootMutation", Fields: map[string]*graphql.Field{}},
+	}
+
+	assert.Equal(t, `schema {
+  query: RootQuery
+}
+
+type RootQuery {
+  version: String!
+}
+`, graphql.PrintSchema(schema))
+}
+
+func TestPrintSchemaStrings(t *testing.T) {
+	descriptions := []string{
+		`Quotes " and backslashes \ are escaped.`,
+		"Control \a and \x1f characters are escaped, é and 😀 are not.",
+		"Spans lines.\nAnd keeps \"\"\" quotes.",
+		"Spans lines\nwith a \t tab and a \r carriage return.",
+		"  Indented\n  lines.",
+		"\nBlank first line.",
+	}
+	fields := map[string]*graphql.Field{}
+	for i, description := range descriptions {
+		fields[fmt.Sprintf("field%d", i)] = &graphql.Field{
+			Type:              &graphql.Scalar{Type: "String"},
+			Description:       description,
+			DeprecationReason: description,
+		}
+	}
+	schema := &graphql.Schema{Query: &graphql.Object{Name: "Query", Fields: fields}}
+
+	// The strings read the same once the schema is parsed.
+	document, err := parser.Parse(parser.ParseParams{Source: graphql.PrintSchema(schema)})
+	require.NoError(t, err)
+	definition := document.Definitions[0].(*ast.ObjectDefinition)
+	for i, field := range definition.Fields {
+		assert.Equal(t, descriptions[i], field.Description.Value)
+		assert.Equal(t, descriptions[i], field.Directives[0].Arguments[0].Value.GetValue())
+	}
+}
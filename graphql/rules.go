@@ -0,0 +1,755 @@
+package graphql
+
+// This file contains the validation rules of the GraphQL specification. The rules inspect the graphql-go ast
+// of the query, so that the errors they report carry the locations of the offending nodes in the source.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"go.appointy.com/jaal/jerrors"
+	"google.golang.org/grpc/codes"
+)
+
+// A Rule checks the operation of a ValidationContext against its schema, and reports the violations it finds.
+type Rule func(c *ValidationContext)
+
+// SpecifiedRules are the validation rules of the GraphQL specification.
+//
+// The rules on unique argument, fragment and input field names, known, unused and cyclic fragments and
+// conflicting aliases are not part of the set, as Parse already rejects the queries breaking them.
+var SpecifiedRules = []Rule{
+	UniqueOperationNames,
+	LoneAnonymousOperation,
+	SingleFieldSubscriptions,
+	KnownTypeNames,
+	FragmentsOnCompositeTypes,
+	VariablesAreInputTypes,
+	ScalarLeafs,
+	FieldsOnCorrectType,
+	PossibleFragmentSpreads,
+	KnownDirectives,
+	UniqueDirectivesPerLocation,
+	KnownArgumentNames,
+	ProvidedRequiredArguments,
+	UniqueVariableNames,
+	NoUndefinedVariables,
+	NoUnusedVariables,
+	VariablesInAllowedPosition,
+}
+
+// Validate checks the operation of query against the schema with the given rules. The violations are returned
+// as a *jerrors.MultiError, with the locations of the nodes they were found at.
+//
+// Validate only applies to queries returned by Parse or by a Document.
+func Validate(schema *Schema, query *Query, rules ...Rule) error {
+	if query.document == nil || query.operation == nil {
+		return nil
+	}
+
+	c := &ValidationContext{
+		Schema:    schema,
+		Document:  query.document,
+		Operation: query.operation,
+		Variables: query.variables,
+		fragments: make(map[string]*ast.FragmentDefinition),
+	}
+	for _, definition := range query.document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, rule := range rules {
+		rule(c)
+	}
+
+	if len(c.errors) == 0 {
+		return nil
+	}
+	return &jerrors.MultiError{Errors: c.errors}
+}
+
+// ValidationContext holds the operation being validated. Rules report violations with Report.
+type ValidationContext struct {
+	Schema    *Schema
+	Document  *ast.Document
+	Operation *ast.OperationDefinition
+	// Variables are the values of the variables of the operation, including their defaults.
+	Variables map[string]interface{}
+
+	fragments map[string]*ast.FragmentDefinition
+	errors    []*jerrors.Error
+}
+
+// Report adds a violation found at the given nodes.
+func (c *ValidationContext) Report(message string, nodes ...ast.Node) {
+	c.report(codes.InvalidArgument, message, nodes)
+}
+
+func (c *ValidationContext) report(code codes.Code, message string, nodes []ast.Node) {
+	var locations []jerrors.Location
+	for _, node := range nodes {
+		if node == nil || node.GetLoc() == nil || node.GetLoc().Source == nil {
+			continue
+		}
+		loc := location.GetLocation(node.GetLoc().Source, node.GetLoc().Start)
+		locations = append(locations, jerrors.Location{Line: loc.Line, Column: loc.Column})
+	}
+
+	c.errors = append(c.errors, &jerrors.Error{
+		Message:    message,
+		Extensions: &jerrors.Extension{Code: code.String()},
+		Paths:      []string{},
+		Locations:  locations,
+	})
+}
+
+// Type returns the named type of the schema, or nil if it does not exist.
+func (c *ValidationContext) Type(name string) Type {
+	return c.Schema.namedTypes()[name]
+}
+
+// Fragment returns the fragment definition of the document, or nil if it does not exist.
+func (c *ValidationContext) Fragment(name string) *ast.FragmentDefinition {
+	return c.fragments[name]
+}
+
+// RootType returns the type the operation is selected on.
+func (c *ValidationContext) RootType() Type {
+	switch c.Operation.Operation {
+	case "mutation":
+		return c.Schema.Mutation
+	case "subscription":
+		return c.Schema.Subscription
+	default:
+		return c.Schema.Query
+	}
+}
+
+// Visitor holds the callbacks of ValidationContext.Walk. Callbacks which are nil are skipped.
+//
+// The types passed to the callbacks are nil when they cannot be known, for example below an unknown field.
+type Visitor struct {
+	// Field is called with the type the field is selected on and the definition of the field, which is nil for
+	// unknown fields.
+	Field func(parent Type, field *ast.Field, definition *Field)
+
+	// InlineFragment and FragmentSpread are called with the type the fragment is selected on.
+	InlineFragment func(parent Type, fragment *ast.InlineFragment)
+	FragmentSpread func(parent Type, spread *ast.FragmentSpread)
+
+	// Directives is called with the directives used at a location, for example "FIELD".
+	Directives func(directives []*ast.Directive, location string)
+
+	// Arguments is called with the arguments passed to a field or a directive, described by owner, and the
+	// arguments it defines, which are nil if it is unknown.
+	Arguments func(node ast.Node, owner string, arguments []*ast.Argument, definitions map[string]Type)
+
+	// Value is called for every value of an argument, including the values nested in lists and objects and the
+	// variables, with the type expected at its position.
+	Value func(value ast.Value, expected Type)
+}
+
+// Walk visits the operation and the fragments it uses, each once.
+func (c *ValidationContext) Walk(v *Visitor) {
+	w := &walker{c: c, v: v, visited: make(map[string]bool)}
+	w.directives(c.Operation.Directives, strings.ToUpper(c.Operation.Operation))
+	w.selectionSet(c.RootType(), c.Operation.SelectionSet)
+}
+
+type walker struct {
+	c       *ValidationContext
+	v       *Visitor
+	visited map[string]bool
+}
+
+func (w *walker) selectionSet(parent Type, selectionSet *ast.SelectionSet) {
+	if selectionSet == nil {
+		return
+	}
+
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			w.field(parent, selection)
+
+		case *ast.InlineFragment:
+			if w.v.InlineFragment != nil {
+				w.v.InlineFragment(parent, selection)
+			}
+			w.directives(selection.Directives, "INLINE_FRAGMENT")
+
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = w.c.Type(selection.TypeCondition.Name.Value)
+			}
+			w.selectionSet(typ, selection.SelectionSet)
+
+		case *ast.FragmentSpread:
+			if w.v.FragmentSpread != nil {
+				w.v.FragmentSpread(parent, selection)
+			}
+			w.directives(selection.Directives, "FRAGMENT_SPREAD")
+
+			name := selection.Name.Value
+			fragment := w.c.Fragment(name)
+			if fragment == nil || w.visited[name] {
+				continue
+			}
+			w.visited[name] = true
+
+			w.directives(fragment.Directives, "FRAGMENT_DEFINITION")
+			w.selectionSet(w.c.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet)
+		}
+	}
+}
+
+func (w *walker) field(parent Type, field *ast.Field) {
+	name := field.Name.Value
+	definition := fieldDefinition(parent, name)
+	if w.v.Field != nil {
+		w.v.Field(parent, field, definition)
+	}
+	w.directives(field.Directives, "FIELD")
+
+	var args map[string]Type
+	var typ Type
+	if definition != nil {
+		args = definition.Args
+		if args == nil {
+			args = map[string]Type{}
+		}
+		typ = namedType(definition.Type)
+	}
+	w.arguments(field, fmt.Sprintf(`field "%s"`, name), field.Arguments, args)
+	w.selectionSet(typ, field.SelectionSet)
+}
+
+func (w *walker) directives(directives []*ast.Directive, location string) {
+	if len(directives) == 0 {
+		return
+	}
+	if w.v.Directives != nil {
+		w.v.Directives(directives, location)
+	}
+
+	for _, directive := range directives {
+		name := directive.Name.Value
+		var args map[string]Type
+		if definition, ok := specifiedDirectives[name]; ok {
+			args = definition.args
+		}
+		w.arguments(directive, fmt.Sprintf(`directive "@%s"`, name), directive.Arguments, args)
+	}
+}
+
+func (w *walker) arguments(node ast.Node, owner string, arguments []*ast.Argument, definitions map[string]Type) {
+	if w.v.Arguments != nil {
+		w.v.Arguments(node, owner, arguments, definitions)
+	}
+	for _, argument := range arguments {
+		w.value(argument.Value, definitions[argument.Name.Value])
+	}
+}
+
+func (w *walker) value(value ast.Value, expected Type) {
+	if w.v.Value != nil {
+		w.v.Value(value, expected)
+	}
+
+	if nonNull, ok := expected.(*NonNull); ok {
+		expected = nonNull.Type
+	}
+
+	switch value := value.(type) {
+	case *ast.ListValue:
+		var elem Type
+		if list, ok := expected.(*List); ok {
+			elem = list.Type
+		}
+		for _, item := range value.Values {
+			w.value(item, elem)
+		}
+
+	case *ast.ObjectValue:
+		var fields map[string]Type
+		if object, ok := expected.(*InputObject); ok {
+			fields = object.InputFields
+		}
+		for _, field := range value.Fields {
+			w.value(field.Value, fields[field.Name.Value])
+		}
+	}
+}
+
+// directiveDefinition describes a directive supported by the executor.
+type directiveDefinition struct {
+	locations []string
+	args      map[string]Type
+}
+
+var specifiedDirectives = map[string]*directiveDefinition{
+	"skip": {
+		locations: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:      map[string]Type{"if": &NonNull{Type: &Scalar{Type: "Boolean"}}},
+	},
+	"include": {
+		locations: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:      map[string]Type{"if": &NonNull{Type: &Scalar{Type: "Boolean"}}},
+	},
+}
+
+// typenameField is the definition of the __typename meta field, which can be selected on any composite type.
+var typenameField = &Field{Type: typenameType}
+
+// fieldDefinition returns the definition of the field selected on parent, or nil if it does not exist.
+func fieldDefinition(parent Type, name string) *Field {
+	if name == "__typename" && isCompositeType(parent) {
+		return typenameField
+	}
+
+	switch parent := parent.(type) {
+	case *Object:
+		return parent.Fields[name]
+	case *Interface:
+		return parent.Fields[name]
+	default:
+		return nil
+	}
+}
+
+// namedType unwraps the lists and non-null types around typ.
+func namedType(typ Type) Type {
+	switch typ := typ.(type) {
+	case *NonNull:
+		return namedType(typ.Type)
+	case *List:
+		return namedType(typ.Type)
+	default:
+		return typ
+	}
+}
+
+func isCompositeType(typ Type) bool {
+	switch typ.(type) {
+	case *Object, *Interface, *Union:
+		return true
+	default:
+		return false
+	}
+}
+
+func isLeafType(typ Type) bool {
+	switch typ.(type) {
+	case *Scalar, *Enum:
+		return true
+	default:
+		return false
+	}
+}
+
+// possibleTypes returns the names of the objects a composite type can resolve to.
+func possibleTypes(typ Type) map[string]bool {
+	types := make(map[string]bool)
+	switch typ := typ.(type) {
+	case *Object:
+		types[typ.Name] = true
+	case *Interface:
+		for name := range typ.Types {
+			types[name] = true
+		}
+	case *Union:
+		for name := range typ.Types {
+			types[name] = true
+		}
+	}
+	return types
+}
+
+// typesOverlap checks if an object can be both of type a and of type b.
+func typesOverlap(a, b Type) bool {
+	possible := possibleTypes(b)
+	for name := range possibleTypes(a) {
+		if possible[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// isTypeSubTypeOf checks if a value of type sub is always a valid value of type super.
+func isTypeSubTypeOf(sub, super Type) bool {
+	if super, ok := super.(*NonNull); ok {
+		if sub, ok := sub.(*NonNull); ok {
+			return isTypeSubTypeOf(sub.Type, super.Type)
+		}
+		return false
+	}
+	if sub, ok := sub.(*NonNull); ok {
+		return isTypeSubTypeOf(sub.Type, super)
+	}
+
+	if super, ok := super.(*List); ok {
+		if sub, ok := sub.(*List); ok {
+			return isTypeSubTypeOf(sub.Type, super.Type)
+		}
+		return false
+	}
+	if _, ok := sub.(*List); ok {
+		return false
+	}
+
+	return sub.String() == super.String()
+}
+
+// operationDescription describes the operation being validated in error messages.
+func operationDescription(operation *ast.OperationDefinition) string {
+	if operation.Name == nil {
+		return "anonymous " + operation.Operation
+	}
+	return fmt.Sprintf(`%s "%s"`, operation.Operation, operation.Name.Value)
+}
+
+// UniqueOperationNames checks that the operations of the document have different names.
+func UniqueOperationNames(c *ValidationContext) {
+	names := make(map[string]*ast.Name)
+	for _, definition := range c.Document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok || operation.Name == nil {
+			continue
+		}
+
+		name := operation.Name.Value
+		if first, ok := names[name]; ok {
+			c.Report(fmt.Sprintf(`there can be only one operation named "%s"`, name), first, operation.Name)
+			continue
+		}
+		names[name] = operation.Name
+	}
+}
+
+// LoneAnonymousOperation checks that an anonymous operation is the only operation of the document.
+func LoneAnonymousOperation(c *ValidationContext) {
+	var operations []*ast.OperationDefinition
+	for _, definition := range c.Document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			operations = append(operations, operation)
+		}
+	}
+	if len(operations) < 2 {
+		return
+	}
+
+	for _, operation := range operations {
+		if operation.Name == nil {
+			c.Report("anonymous operation must be the only defined operation", operation)
+		}
+	}
+}
+
+// SingleFieldSubscriptions checks that a subscription selects a single root field.
+func SingleFieldSubscriptions(c *ValidationContext) {
+	if c.Operation.Operation != "subscription" || c.Operation.SelectionSet == nil {
+		return
+	}
+
+	selections := c.Operation.SelectionSet.Selections
+	if len(selections) > 1 {
+		nodes := make([]ast.Node, 0, len(selections)-1)
+		for _, selection := range selections[1:] {
+			nodes = append(nodes, selection.(ast.Node))
+		}
+		c.Report(fmt.Sprintf("%s must select only one top level field", operationDescription(c.Operation)), nodes...)
+	}
+}
+
+// KnownTypeNames checks that the types of variables and the type conditions of fragments exist in the schema.
+func KnownTypeNames(c *ValidationContext) {
+	checkType := func(name *ast.Name) {
+		if c.Type(name.Value) == nil {
+			c.Report(fmt.Sprintf(`unknown type "%s"`, name.Value), name)
+		}
+	}
+
+	for _, definition := range c.Operation.VariableDefinitions {
+		checkType(astNamedType(definition.Type).Name)
+	}
+	for _, definition := range c.Document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			checkType(fragment.TypeCondition.Name)
+		}
+	}
+
+	c.Walk(&Visitor{
+		InlineFragment: func(parent Type, fragment *ast.InlineFragment) {
+			if fragment.TypeCondition != nil {
+				checkType(fragment.TypeCondition.Name)
+			}
+		},
+	})
+}
+
+// FragmentsOnCompositeTypes checks that fragments are only used on objects, interfaces and unions.
+func FragmentsOnCompositeTypes(c *ValidationContext) {
+	for _, definition := range c.Document.Definitions {
+		fragment, ok := definition.(*ast.FragmentDefinition)
+		if !ok {
+			continue
+		}
+		typ := c.Type(fragment.TypeCondition.Name.Value)
+		if typ != nil && !isCompositeType(typ) {
+			c.Report(fmt.Sprintf(`fragment "%s" cannot condition on non composite type "%s"`, fragment.Name.Value, typ), fragment.TypeCondition)
+		}
+	}
+
+	c.Walk(&Visitor{
+		InlineFragment: func(parent Type, fragment *ast.InlineFragment) {
+			if fragment.TypeCondition == nil {
+				return
+			}
+			typ := c.Type(fragment.TypeCondition.Name.Value)
+			if typ != nil && !isCompositeType(typ) {
+				c.Report(fmt.Sprintf(`fragment cannot condition on non composite type "%s"`, typ), fragment.TypeCondition)
+			}
+		},
+	})
+}
+
+// VariablesAreInputTypes checks that variables are declared with scalars, enums or input objects.
+func VariablesAreInputTypes(c *ValidationContext) {
+	for _, definition := range c.Operation.VariableDefinitions {
+		typ := c.Type(astNamedType(definition.Type).Name.Value)
+		if typ != nil && !isInputType(typ) {
+			c.Report(fmt.Sprintf(`variable "$%s" cannot be non-input type "%s"`, definition.Variable.Name.Value, typeRefFromAST(definition.Type)), definition.Type)
+		}
+	}
+}
+
+// ScalarLeafs checks that scalars and enums have no selections, and that other fields have selections.
+func ScalarLeafs(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Field: func(parent Type, field *ast.Field, definition *Field) {
+			if definition == nil {
+				return
+			}
+
+			typ := namedType(definition.Type)
+			if isLeafType(typ) && field.SelectionSet != nil {
+				c.Report(fmt.Sprintf(`field "%s" must not have a selection since type "%s" has no subfields`, field.Name.Value, definition.Type), field.SelectionSet)
+			}
+			if !isLeafType(typ) && field.SelectionSet == nil {
+				c.Report(fmt.Sprintf(`field "%s" of type "%s" must have a selection of subfields`, field.Name.Value, definition.Type), field)
+			}
+		},
+	})
+}
+
+// FieldsOnCorrectType checks that the selected fields are defined by the type they are selected on.
+func FieldsOnCorrectType(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Field: func(parent Type, field *ast.Field, definition *Field) {
+			if isCompositeType(parent) && definition == nil {
+				c.Report(fmt.Sprintf(`cannot query field "%s" on type "%s"`, field.Name.Value, parent), field)
+			}
+		},
+	})
+}
+
+// PossibleFragmentSpreads checks that fragments are only spread where the type condition can apply.
+func PossibleFragmentSpreads(c *ValidationContext) {
+	check := func(parent Type, condition *ast.Named) bool {
+		if !isCompositeType(parent) || condition == nil {
+			return true
+		}
+		typ := c.Type(condition.Name.Value)
+		return !isCompositeType(typ) || typesOverlap(parent, typ)
+	}
+
+	c.Walk(&Visitor{
+		InlineFragment: func(parent Type, fragment *ast.InlineFragment) {
+			if !check(parent, fragment.TypeCondition) {
+				c.Report(fmt.Sprintf(`fragment cannot be spread here as objects of type "%s" can never be of type "%s"`, parent, fragment.TypeCondition.Name.Value), fragment)
+			}
+		},
+		FragmentSpread: func(parent Type, spread *ast.FragmentSpread) {
+			fragment := c.Fragment(spread.Name.Value)
+			if fragment != nil && !check(parent, fragment.TypeCondition) {
+				c.Report(fmt.Sprintf(`fragment "%s" cannot be spread here as objects of type "%s" can never be of type "%s"`, spread.Name.Value, parent, fragment.TypeCondition.Name.Value), spread)
+			}
+		},
+	})
+}
+
+// KnownDirectives checks that the directives are supported and used at valid locations.
+func KnownDirectives(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Directives: func(directives []*ast.Directive, location string) {
+			for _, directive := range directives {
+				name := directive.Name.Value
+				definition, ok := specifiedDirectives[name]
+				if !ok {
+					c.Report(fmt.Sprintf(`unknown directive "@%s"`, name), directive)
+					continue
+				}
+				if !contains(definition.locations, location) {
+					c.Report(fmt.Sprintf(`directive "@%s" may not be used on %s`, name, location), directive)
+				}
+			}
+		},
+	})
+}
+
+// UniqueDirectivesPerLocation checks that a directive is used at most once at each location.
+func UniqueDirectivesPerLocation(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Directives: func(directives []*ast.Directive, location string) {
+			seen := make(map[string]*ast.Directive)
+			for _, directive := range directives {
+				name := directive.Name.Value
+				if first, ok := seen[name]; ok {
+					c.Report(fmt.Sprintf(`the directive "@%s" can only be used once at this location`, name), first, directive)
+					continue
+				}
+				seen[name] = directive
+			}
+		},
+	})
+}
+
+// KnownArgumentNames checks that the arguments passed to fields and directives are defined by them.
+func KnownArgumentNames(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Arguments: func(node ast.Node, owner string, arguments []*ast.Argument, definitions map[string]Type) {
+			if definitions == nil {
+				return
+			}
+			for _, argument := range arguments {
+				if _, ok := definitions[argument.Name.Value]; !ok {
+					c.Report(fmt.Sprintf(`unknown argument "%s" on %s`, argument.Name.Value, owner), argument)
+				}
+			}
+		},
+	})
+}
+
+// ProvidedRequiredArguments checks that the non-null arguments of fields and directives are provided.
+func ProvidedRequiredArguments(c *ValidationContext) {
+	c.Walk(&Visitor{
+		Arguments: func(node ast.Node, owner string, arguments []*ast.Argument, definitions map[string]Type) {
+			provided := make(map[string]bool, len(arguments))
+			for _, argument := range arguments {
+				provided[argument.Name.Value] = true
+			}
+
+			names := make([]string, 0, len(definitions))
+			for name := range definitions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				if _, ok := definitions[name].(*NonNull); ok && !provided[name] {
+					c.Report(fmt.Sprintf(`argument "%s" of type "%s" is required by %s but was not provided`, name, definitions[name], owner), node)
+				}
+			}
+		},
+	})
+}
+
+// UniqueVariableNames checks that the variables of the operation have different names.
+func UniqueVariableNames(c *ValidationContext) {
+	names := make(map[string]*ast.Variable)
+	for _, definition := range c.Operation.VariableDefinitions {
+		name := definition.Variable.Name.Value
+		if first, ok := names[name]; ok {
+			c.Report(fmt.Sprintf(`there can be only one variable named "$%s"`, name), first, definition.Variable)
+			continue
+		}
+		names[name] = definition.Variable
+	}
+}
+
+// NoUndefinedVariables checks that the variables used by the operation are declared by it.
+func NoUndefinedVariables(c *ValidationContext) {
+	defined := make(map[string]bool)
+	for _, definition := range c.Operation.VariableDefinitions {
+		defined[definition.Variable.Name.Value] = true
+	}
+
+	c.Walk(&Visitor{
+		Value: func(value ast.Value, expected Type) {
+			if variable, ok := value.(*ast.Variable); ok && !defined[variable.Name.Value] {
+				c.Report(fmt.Sprintf(`variable "$%s" is not defined by %s`, variable.Name.Value, operationDescription(c.Operation)), variable, c.Operation)
+			}
+		},
+	})
+}
+
+// NoUnusedVariables checks that the variables declared by the operation are used.
+func NoUnusedVariables(c *ValidationContext) {
+	used := make(map[string]bool)
+	c.Walk(&Visitor{
+		Value: func(value ast.Value, expected Type) {
+			if variable, ok := value.(*ast.Variable); ok {
+				used[variable.Name.Value] = true
+			}
+		},
+	})
+
+	for _, definition := range c.Operation.VariableDefinitions {
+		if name := definition.Variable.Name.Value; !used[name] {
+			c.Report(fmt.Sprintf(`variable "$%s" is never used in %s`, name, operationDescription(c.Operation)), definition)
+		}
+	}
+}
+
+// VariablesInAllowedPosition checks that variables are only used where their type is expected.
+func VariablesInAllowedPosition(c *ValidationContext) {
+	definitions := make(map[string]*ast.VariableDefinition)
+	for _, definition := range c.Operation.VariableDefinitions {
+		definitions[definition.Variable.Name.Value] = definition
+	}
+
+	c.Walk(&Visitor{
+		Value: func(value ast.Value, expected Type) {
+			variable, ok := value.(*ast.Variable)
+			if !ok || expected == nil {
+				return
+			}
+			definition, ok := definitions[variable.Name.Value]
+			if !ok {
+				return
+			}
+			typ, err := c.Schema.variableType(typeRefFromAST(definition.Type))
+			if err != nil {
+				return
+			}
+
+			// A nullable variable with a default value can be used where a non-null value is expected.
+			if _, ok := typ.(*NonNull); !ok && definition.DefaultValue != nil {
+				typ = &NonNull{Type: typ}
+			}
+			if !isTypeSubTypeOf(typ, expected) {
+				c.Report(fmt.Sprintf(`variable "$%s" of type "%s" used in position expecting type "%s"`, variable.Name.Value, typeRefFromAST(definition.Type), expected), definition, variable)
+			}
+		},
+	})
+}
+
+// astNamedType unwraps the lists and non-null types around a graphql-go ast type.
+func astNamedType(typ ast.Type) *ast.Named {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		return astNamedType(typ.Type)
+	case *ast.List:
+		return astNamedType(typ.Type)
+	case *ast.Named:
+		return typ
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,227 @@
+package graphql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/stretchr/testify/assert"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+)
+
+func makeValidationSchema() *graphql.Schema {
+	str := &graphql.Scalar{Type: "String"}
+	episode := &graphql.Enum{Type: "Episode", Values: []string{"NEWHOPE", "EMPIRE"}}
+
+	human := &graphql.Object{
+		Name: "Human",
+		Fields: map[string]*graphql.Field{
+			"name":   {Type: &graphql.NonNull{Type: str}},
+			"height": {Type: &graphql.Scalar{Type: "Float"}},
+		},
+	}
+	droid := &graphql.Object{
+		Name: "Droid",
+		Fields: map[string]*graphql.Field{
+			"name":            {Type: &graphql.NonNull{Type: str}},
+			"primaryFunction": {Type: str},
+		},
+	}
+	character := &graphql.Interface{
+		Name:   "Character",
+		Types:  map[string]*graphql.Object{"Human": human, "Droid": droid},
+		Fields: map[string]*graphql.Field{"name": {Type: &graphql.NonNull{Type: str}}},
+	}
+	searchResult := &graphql.Union{
+		Name:  "SearchResult",
+		Types: map[string]*graphql.Object{"Human": human, "Droid": droid},
+	}
+
+	return &graphql.Schema{
+		Query: &graphql.Object{
+			Name: "Query",
+			Fields: map[string]*graphql.Field{
+				"hero": {
+					Type: character,
+					Args: map[string]graphql.Type{"episode": episode},
+				},
+				"search": {
+					Type: &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: searchResult}}},
+					Args: map[string]graphql.Type{"text": &graphql.NonNull{Type: str}},
+					ParseArguments: func(json interface{}) (interface{}, error) {
+						return json, nil
+					},
+				},
+				"human": {
+					Type: human,
+					Args: map[string]graphql.Type{"id": &graphql.NonNull{Type: &graphql.Scalar{Type: "ID"}}},
+				},
+				"count": {Type: &graphql.Scalar{Type: "Int"}},
+			},
+		},
+		Subscription: &graphql.Object{
+			Name: "Subscription",
+			Fields: map[string]*graphql.Field{
+				"a": {Type: &graphql.Scalar{Type: "Int"}},
+				"b": {Type: &graphql.Scalar{Type: "Int"}},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := makeValidationSchema()
+
+	cases := []struct {
+		name   string
+		query  string
+		errors []string
+	}{
+		{
+			name: "valid",
+			query: `query Q($e: Episode = EMPIRE, $t: String!, $skip: Boolean!) {
+				hero(episode: $e) { __typename name ... on Human { height } ...droid }
+				search(text: $t) { ... on Character { name } ... on Droid { primaryFunction } }
+				count @skip(if: $skip)
+			}
+			fragment droid on Droid { primaryFunction }`,
+		},
+		{
+			name:   "unknown field",
+			query:  `{ count hero { name mass } }`,
+			errors: []string{`cannot query field "mass" on type "Character"`},
+		},
+		{
+			name:   "fragment on unknown type",
+			query:  `{ search(text: "a") { ... on Wookie { name } } }`,
+			errors: []string{`unknown type "Wookie"`},
+		},
+		{
+			name:   "fragment on scalar",
+			query:  `{ count ...F } fragment F on Int { x }`,
+			errors: []string{`fragment "F" cannot condition on non composite type "Int"`},
+		},
+		{
+			name:   "impossible spread",
+			query:  `{ human(id: "1") { ... on Droid { primaryFunction } } }`,
+			errors: []string{`fragment cannot be spread here as objects of type "Human" can never be of type "Droid"`},
+		},
+		{
+			name:  "arguments",
+			query: `{ human(name: "x") { name } }`,
+			errors: []string{
+				`unknown argument "name" on field "human"`,
+				`argument "id" of type "ID!" is required by field "human" but was not provided`,
+			},
+		},
+		{
+			name:  "scalar leafs",
+			query: `{ count { x } hero }`,
+			errors: []string{
+				`field "count" must not have a selection since type "Int" has no subfields`,
+				`field "hero" of type "Character" must have a selection of subfields`,
+			},
+		},
+		{
+			name:  "directives",
+			query: `{ count @skip(if: true) @skip(if: false) @foo a: count @include }`,
+			errors: []string{
+				`unknown directive "@foo"`,
+				`the directive "@skip" can only be used once at this location`,
+				`argument "if" of type "Boolean!" is required by directive "@include" but was not provided`,
+			},
+		},
+		{
+			name:  "variables",
+			query: `query Q($e: Episode, $t: String, $u: Int, $u: Int, $h: Human) { hero(episode: $e) { name } search(text: $t) { __typename } human(id: $x) { name } }`,
+			errors: []string{
+				`variable "$h" cannot be non-input type "Human"`,
+				`there can be only one variable named "$u"`,
+				`variable "$x" is not defined by query "Q"`,
+				`variable "$u" is never used in query "Q"`,
+				`variable "$u" is never used in query "Q"`,
+				`variable "$h" is never used in query "Q"`,
+				`variable "$t" of type "String" used in position expecting type "String!"`,
+			},
+		},
+		{
+			name:   "subscription",
+			query:  `subscription S { a b }`,
+			errors: []string{`subscription "S" must select only one top level field`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := graphql.Parse(c.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var messages []string
+			if err := graphql.Validate(schema, query, graphql.SpecifiedRules...); err != nil {
+				for _, e := range err.(*jerrors.MultiError).Errors {
+					messages = append(messages, e.Message)
+				}
+			}
+			assert.Equal(t, c.errors, messages)
+		})
+	}
+}
+
+func TestValidateLocations(t *testing.T) {
+	query, err := graphql.Parse(`query Q($t: String) {
+	search(text: $t) {
+		... on Human { mass }
+	}
+}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = graphql.Validate(makeValidationSchema(), query, graphql.SpecifiedRules...)
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{
+			Message:    `cannot query field "mass" on type "Human"`,
+			Extensions: &jerrors.Extension{Code: "InvalidArgument"},
+			Paths:      []string{},
+			Locations:  []jerrors.Location{{Line: 3, Column: 18}},
+		},
+		{
+			Message:    `variable "$t" of type "String" used in position expecting type "String!"`,
+			Extensions: &jerrors.Extension{Code: "InvalidArgument"},
+			Paths:      []string{},
+			Locations:  []jerrors.Location{{Line: 1, Column: 9}, {Line: 2, Column: 15}},
+		},
+	}}, err)
+
+	// Custom rules are run alongside the specified ones.
+	disableSearch := func(c *graphql.ValidationContext) {
+		c.Walk(&graphql.Visitor{
+			Field: func(parent graphql.Type, field *ast.Field, definition *graphql.Field) {
+				if field.Name.Value == "search" {
+					c.Report("search is disabled", field)
+				}
+			},
+		})
+	}
+	query, err = graphql.Parse(`{ search(text: "a") { __typename } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = graphql.Validate(makeValidationSchema(), query, append(graphql.SpecifiedRules, disableSearch)...)
+	assert.Equal(t, []jerrors.Location{{Line: 1, Column: 3}}, err.(*jerrors.MultiError).Errors[0].Locations)
+}
+
+func TestValidateQueryUnknownFragmentType(t *testing.T) {
+	query, err := graphql.Parse(`{ search(text: "a") { ... on Wookie { name } } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = graphql.ValidateQuery(context.Background(), makeValidationSchema().Query, query.SelectionSet)
+	if err == nil || err.Error() != `fragment on "Wookie" can never apply to "SearchResult"` {
+		t.Error("expected fragment on unknown type to fail", err)
+	}
+}
@@ -3,6 +3,7 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"sync"
 )
 
 // Type represents a GraphQL type, and should be either an Object, a Scalar,
@@ -33,6 +34,11 @@ type Enum struct {
 	Type       string
 	Values     []string
 	ReverseMap map[interface{}]string
+
+	// ValueDescriptions and ValueDeprecationReasons document the values of the enum by name. A value is
+	// deprecated when it has a deprecation reason.
+	ValueDescriptions       map[string]string
+	ValueDeprecationReasons map[string]string
 }
 
 func (e *Enum) isType() {}
@@ -75,6 +81,9 @@ func (l *List) String() string {
 type InputObject struct {
 	Name        string
 	InputFields map[string]Type
+
+	// FieldDescriptions documents the input fields by name.
+	FieldDescriptions map[string]string
 }
 
 func (io *InputObject) isType() {}
@@ -137,18 +146,43 @@ type Resolver func(ctx context.Context, source, args interface{}, selectionSet *
 // A BatchResolver calculates the value of a field for a slice of objects.
 type BatchResolver func(ctx context.Context, sources []interface{}, args interface{}, selectionSet *SelectionSet) ([]interface{}, error)
 
+// DefaultDeprecationReason is the deprecation reason of the specification, used when none is given. PrintSchema
+// omits it.
+const DefaultDeprecationReason = "No longer supported"
+
 // Field knows how to compute field values of an Object
 //
 // Fields are responsible for computing their value themselves.
 type Field struct {
-	Resolve        Resolver
+	Resolve Resolver
+
+	// BatchResolver, when set, resolves the field for all the sources found at the same
+	// path of a query at once. Resolve is still used for a field selected on a single object.
+	BatchResolver BatchResolver
+
 	Type           Type
 	Args           map[string]Type
 	ParseArguments func(json interface{}) (interface{}, error)
 
+	// Description, ArgDescriptions and DeprecationReason document the field for introspection. The field is
+	// deprecated when it has a deprecation reason, which is DefaultDeprecationReason unless given.
+	Description       string
+	ArgDescriptions   map[string]string
+	DeprecationReason string
+
 	External  bool
 	Expensive bool
 
+	// Cost is the cost of resolving the field, which adds up to the complexity of a query.
+	Cost int
+	// CostMultipliers are the arguments bounding the length of the list returned by the field, such as "first".
+	// The complexity of the selections of the field is multiplied by their values.
+	CostMultipliers []string
+
+	// Filter, set on the fields of subscriptions, checks if an event with payload is relevant to a subscription
+	// selecting the field with the parsed args. The events it rejects are not executed.
+	Filter func(ctx context.Context, args interface{}, payload []byte) bool
+
 	LazyExecution bool
 	LazyResolver  func(ctx context.Context, fun interface{}) (interface{}, error)
 }
@@ -158,6 +192,11 @@ type Schema struct {
 	Query        Type
 	Mutation     Type
 	Subscription Type
+
+	// mu guards the named types, which are cached for the root types they were computed from.
+	mu        sync.Mutex
+	typeMap   map[string]Type
+	typeRoots [3]Type
 }
 
 // SelectionSet represents a core GraphQL query
@@ -201,6 +240,8 @@ type Selection struct {
 	SelectionSet *SelectionSet
 	Directives   []*Directive
 
+	// UseBatch is set during validation when the selected field has a BatchResolver,
+	// so that the executor resolves the selection for many sources at once.
 	UseBatch bool
 
 	// The parsed flag is used to make sure the args for this Selection are only
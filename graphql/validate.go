@@ -6,6 +6,9 @@ import (
 )
 
 // ValidateQuery checks that the given selectionSet matches the schema typ, and parses the args in selectionSet
+//
+// The args are replaced by their parsed values, so selectionSet must belong to a query returned by Parse or Bind,
+// which is owned by a single request. The prepared query it was bound from is left unchanged.
 func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) error {
 	switch typ := typ.(type) {
 	case *Scalar:
@@ -24,6 +27,9 @@ func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) er
 		}
 
 		for _, fragment := range selectionSet.Fragments {
+			if _, ok := typ.Types[fragment.Fragment.On]; !ok && fragment.Fragment.On != typ.Name {
+				return fmt.Errorf(`fragment on "%s" can never apply to "%s"`, fragment.Fragment.On, typ.Name)
+			}
 			for typString, graphqlTyp := range typ.Types {
 				if fragment.Fragment.On != typString {
 					continue
@@ -55,6 +61,9 @@ func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) er
 			return fmt.Errorf("object field must have selections")
 		}
 		for _, fragment := range selectionSet.Fragments {
+			if _, ok := typ.Types[fragment.Fragment.On]; !ok && fragment.Fragment.On != typ.Name {
+				return fmt.Errorf(`fragment on "%s" can never apply to "%s"`, fragment.Fragment.On, typ.Name)
+			}
 			for typString, graphqlTyp := range typ.Types {
 				if fragment.Fragment.On != typString {
 					continue
@@ -85,6 +94,7 @@ func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) er
 					return fmt.Errorf(`error parsing args for "%s": %s`, selection.Name, err)
 				}
 				selection.Args = parsed
+				selection.UseBatch = field.BatchResolver != nil
 				selection.parsed = true
 			}
 			if err := ValidateQuery(ctx, field.Type, selection.SelectionSet); err != nil {
@@ -120,6 +130,7 @@ func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) er
 					return fmt.Errorf(`error parsing args for "%s": %s`, selection.Name, err)
 				}
 				selection.Args = parsed
+				selection.UseBatch = field.BatchResolver != nil
 				selection.parsed = true
 			}
 
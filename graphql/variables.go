@@ -0,0 +1,294 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// VariableDefinition is a variable declared by an operation, for example `$id: ID!`.
+type VariableDefinition struct {
+	Name         string
+	Type         *TypeRef
+	DefaultValue interface{}
+}
+
+// TypeRef is a type as written in a query. It refers to a named type of the schema, which may be wrapped
+// in lists and non-null types.
+type TypeRef struct {
+	// Name is the name of the referred type. It is empty for lists.
+	Name string
+	// Elem is the type of the elements of a list.
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = fmt.Sprintf("[%s]", t.Elem)
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// typeRefFromAST converts a graphql-go ast type into a *TypeRef.
+func typeRefFromAST(typ ast.Type) *TypeRef {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		ref := typeRefFromAST(typ.Type)
+		ref.NonNull = true
+		return ref
+	case *ast.List:
+		return &TypeRef{Elem: typeRefFromAST(typ.Type)}
+	case *ast.Named:
+		return &TypeRef{Name: typ.Name.Value}
+	default:
+		return &TypeRef{}
+	}
+}
+
+// builtinScalars are the scalars defined by the spec, which can always be used as variable types.
+var builtinScalars = map[string]*Scalar{
+	"Int":     {Type: "Int"},
+	"Float":   {Type: "Float"},
+	"String":  {Type: "String"},
+	"Boolean": {Type: "Boolean"},
+	"ID":      {Type: "ID"},
+}
+
+// ValidateVariables checks that the values in vars match the types of the variables declared by the query,
+// and that every required variable is provided. It should be called before ValidateQuery, which parses the
+// arguments the variables were bound to.
+func ValidateVariables(schema *Schema, query *Query, vars map[string]interface{}) error {
+	for _, definition := range query.Variables {
+		typ, err := schema.variableType(definition.Type)
+		if err != nil {
+			return fmt.Errorf(`variable "$%s" %s`, definition.Name, err)
+		}
+
+		value, ok := vars[definition.Name]
+		if !ok {
+			if definition.DefaultValue == nil {
+				if definition.Type.NonNull {
+					return fmt.Errorf(`variable "$%s" of required type "%s" was not provided`, definition.Name, definition.Type)
+				}
+				continue
+			}
+			value = definition.DefaultValue
+		}
+
+		if err := coerceVariable(typ, value); err != nil {
+			return fmt.Errorf(`variable "$%s" got invalid value %s: %s`, definition.Name, formatValue(value), err)
+		}
+	}
+
+	return nil
+}
+
+// variableType resolves ref into the input type of the schema it refers to.
+func (s *Schema) variableType(ref *TypeRef) (Type, error) {
+	var typ Type
+	if ref.Elem != nil {
+		elem, err := s.variableType(ref.Elem)
+		if err != nil {
+			return nil, err
+		}
+		typ = &List{Type: elem}
+	} else {
+		named, ok := s.namedTypes()[ref.Name]
+		if !ok {
+			return nil, fmt.Errorf(`has unknown type "%s"`, ref.Name)
+		}
+		if !isInputType(named) {
+			return nil, fmt.Errorf(`has type "%s" which is not an input type`, ref.Name)
+		}
+		typ = named
+	}
+
+	if ref.NonNull {
+		typ = &NonNull{Type: typ}
+	}
+	return typ, nil
+}
+
+// namedTypes returns the named types of the schema by name, which are computed once for its root types.
+func (s *Schema) namedTypes() map[string]Type {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roots := [...]Type{s.Query, s.Mutation, s.Subscription}
+	if s.typeMap != nil && s.typeRoots == roots {
+		return s.typeMap
+	}
+
+	types := make(map[string]Type)
+	for name, scalar := range builtinScalars {
+		types[name] = scalar
+	}
+
+	var collect func(Type)
+	collect = func(typ Type) {
+		switch typ := typ.(type) {
+		case *NonNull:
+			collect(typ.Type)
+			return
+		case *List:
+			collect(typ.Type)
+			return
+		case nil:
+			return
+		}
+
+		if _, ok := types[typ.String()]; ok {
+			return
+		}
+		types[typ.String()] = typ
+
+		var fields map[string]*Field
+		switch typ := typ.(type) {
+		case *Object:
+			fields = typ.Fields
+		case *Interface:
+			fields = typ.Fields
+			for _, obj := range typ.Types {
+				collect(obj)
+			}
+		case *Union:
+			for _, obj := range typ.Types {
+				collect(obj)
+			}
+		case *InputObject:
+			for _, field := range typ.InputFields {
+				collect(field)
+			}
+		}
+
+		for _, field := range fields {
+			for _, arg := range field.Args {
+				collect(arg)
+			}
+			collect(field.Type)
+		}
+	}
+
+	for _, root := range roots {
+		collect(root)
+	}
+
+	s.typeMap, s.typeRoots = types, roots
+	return types
+}
+
+// coerceVariable checks that the JSON value can be used as a value of typ.
+func coerceVariable(typ Type, value interface{}) error {
+	if nonNull, ok := typ.(*NonNull); ok {
+		if value == nil {
+			return fmt.Errorf("expected non-null value of type %s", nonNull)
+		}
+		typ = nonNull.Type
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch typ := typ.(type) {
+	case *Scalar:
+		if !isValidScalarValue(typ.Type, value) {
+			return fmt.Errorf("expected type %s", typ.Type)
+		}
+
+	case *Enum:
+		name, ok := value.(string)
+		if !ok || !contains(typ.Values, name) {
+			return fmt.Errorf("expected type %s", typ.Type)
+		}
+
+	case *List:
+		list, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced into a list of one element.
+			return coerceVariable(typ.Type, value)
+		}
+		for i, item := range list {
+			if err := coerceVariable(typ.Type, item); err != nil {
+				return fmt.Errorf("in element #%d: %s", i, err)
+			}
+		}
+
+	case *InputObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected type %s to be an object", typ.Name)
+		}
+		for name := range object {
+			if _, ok := typ.InputFields[name]; !ok {
+				return fmt.Errorf(`field "%s" is not defined by type %s`, name, typ.Name)
+			}
+		}
+		for name, fieldTyp := range typ.InputFields {
+			if err := coerceVariable(fieldTyp, object[name]); err != nil {
+				return fmt.Errorf(`in field "%s": %s`, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isValidScalarValue checks the JSON value of a builtin scalar. The values of custom scalars are checked
+// when they are unmarshalled.
+func isValidScalarValue(name string, value interface{}) bool {
+	switch name {
+	case "Int":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	case "Float":
+		_, ok := value.(float64)
+		return ok
+	case "String", "ID":
+		_, ok := value.(string)
+		return ok
+	case "Boolean":
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+// isInputType checks if typ can be the type of an argument or a variable.
+func isInputType(typ Type) bool {
+	switch typ := typ.(type) {
+	case *NonNull:
+		return isInputType(typ.Type)
+	case *List:
+		return isInputType(typ.Type)
+	case *Scalar, *Enum, *InputObject:
+		return true
+	default:
+		return false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue formats a JSON value for an error message.
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
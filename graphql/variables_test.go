@@ -0,0 +1,130 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+func TestValidateVariables(t *testing.T) {
+	color := &graphql.Enum{Type: "Color", Values: []string{"RED", "GREEN"}}
+	filter := &graphql.InputObject{
+		Name: "Filter",
+		InputFields: map[string]graphql.Type{
+			"name":   &graphql.NonNull{Type: &graphql.Scalar{Type: "String"}},
+			"colors": &graphql.List{Type: &graphql.NonNull{Type: color}},
+		},
+	}
+	schema := &graphql.Schema{
+		Query: &graphql.Object{
+			Name: "Query",
+			Fields: map[string]*graphql.Field{
+				"search": {
+					Type: &graphql.Scalar{Type: "String"},
+					Args: map[string]graphql.Type{
+						"filter": filter,
+						"limit":  &graphql.Scalar{Type: "Int"},
+						"at":     &graphql.Scalar{Type: "Timestamp"},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		query  string
+		vars   map[string]interface{}
+		expect string
+	}{
+		{
+			name:  "valid",
+			query: `query($f: Filter!, $l: Int, $at: Timestamp) { search(filter: $f, limit: $l, at: $at) }`,
+			vars: map[string]interface{}{
+				"f":  map[string]interface{}{"name": "a", "colors": []interface{}{"RED"}},
+				"l":  float64(10),
+				"at": "2019-01-01T00:00:00Z",
+			},
+		},
+		{
+			name:  "single value coerced to list",
+			query: `query($f: Filter) { search(filter: $f) }`,
+			vars:  map[string]interface{}{"f": map[string]interface{}{"name": "a", "colors": "GREEN"}},
+		},
+		{
+			name:  "optional variable not provided",
+			query: `query($l: Int) { search(limit: $l) }`,
+		},
+		{
+			name:   "required variable not provided",
+			query:  `query($f: Filter!) { search(filter: $f) }`,
+			expect: `variable "$f" of required type "Filter!" was not provided`,
+		},
+		{
+			name:   "null for required variable",
+			query:  `query($f: Filter!) { search(filter: $f) }`,
+			vars:   map[string]interface{}{"f": nil},
+			expect: `variable "$f" got invalid value null: expected non-null value of type Filter!`,
+		},
+		{
+			name:   "fractional int",
+			query:  `query($l: Int) { search(limit: $l) }`,
+			vars:   map[string]interface{}{"l": 1.5},
+			expect: `variable "$l" got invalid value 1.5: expected type Int`,
+		},
+		{
+			name:   "invalid default value",
+			query:  `query($l: Int = "ten") { search(limit: $l) }`,
+			expect: `variable "$l" got invalid value "ten": expected type Int`,
+		},
+		{
+			name:   "unknown enum value",
+			query:  `query($f: Filter) { search(filter: $f) }`,
+			vars:   map[string]interface{}{"f": map[string]interface{}{"name": "a", "colors": []interface{}{"BLUE"}}},
+			expect: `variable "$f" got invalid value {"colors":["BLUE"],"name":"a"}: in field "colors": in element #0: expected type Color`,
+		},
+		{
+			name:   "unknown input field",
+			query:  `query($f: Filter) { search(filter: $f) }`,
+			vars:   map[string]interface{}{"f": map[string]interface{}{"name": "a", "size": float64(1)}},
+			expect: `variable "$f" got invalid value {"name":"a","size":1}: field "size" is not defined by type Filter`,
+		},
+		{
+			name:   "missing required input field",
+			query:  `query($f: Filter) { search(filter: $f) }`,
+			vars:   map[string]interface{}{"f": map[string]interface{}{}},
+			expect: `variable "$f" got invalid value {}: in field "name": expected non-null value of type String!`,
+		},
+		{
+			name:   "unknown type",
+			query:  `query($l: int64) { search(limit: $l) }`,
+			vars:   map[string]interface{}{"l": float64(1)},
+			expect: `variable "$l" has unknown type "int64"`,
+		},
+		{
+			name:   "output type",
+			query:  `query($q: Query) { search(limit: $q) }`,
+			expect: `variable "$q" has type "Query" which is not an input type`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := graphql.Parse(c.query, c.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateVariables(schema, query, c.vars)
+			if c.expect == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.expect {
+				t.Errorf("expected error %q, got %v", c.expect, err)
+			}
+		})
+	}
+}
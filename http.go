@@ -1,10 +1,18 @@
 package jaal
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"go.appointy.com/jaal/graphql"
 	"go.appointy.com/jaal/jerrors"
@@ -13,21 +21,119 @@ import (
 type HandlerOption func(*handlerOptions)
 
 type handlerOptions struct {
-	Middlewares []MiddlewareFunc
+	Middlewares           []MiddlewareFunc
+	MaxConcurrency        int
+	ValidationRules       []graphql.Rule
+	MaxDepth              int
+	MaxAliases            int
+	MaxRootFields         int
+	MaxComplexity         int
+	CacheControl          string
+	MaxBatchSize          int
+	QueryStore            QueryStore
+	TrustedDocuments      *TrustedDocuments
+	QueryCacheSize        int
+	MaxUploadSize         int64
+	UploadMemory          int64
+	Playground            bool
+	KeepAlive             time.Duration
+	IdleTimeout           time.Duration
+	MaxConnectionLifetime time.Duration
+	MaxSubscriptions      int
+	SubscriptionBuffer    int
+	OverflowPolicy        OverflowPolicy
+	ConnectionInit        ConnectionInitFunc
+}
+
+const (
+	// defaultMaxBatchSize is the maximum number of operations of a batch, unless set with WithMaxBatchSize.
+	defaultMaxBatchSize = 10
+	// defaultQueryCacheSize is the number of prepared operations cached, unless set with WithQueryCacheSize.
+	defaultQueryCacheSize = 1000
+)
+
+// WithMaxConcurrency enables the concurrent execution of the sibling fields and list elements of
+// a query, running at most n resolvers in parallel for every request and every subscription event.
+// Mutation root fields are always executed serially.
+func WithMaxConcurrency(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxConcurrency = n
+	}
+}
+
+// WithValidationRules replaces the rules queries are validated with, which default to graphql.SpecifiedRules.
+// Custom rules can be added with WithValidationRules(append(graphql.SpecifiedRules, rule)...). The rules are
+// checked once per cached operation, before its variables are bound, so they see no variable values.
+func WithValidationRules(rules ...graphql.Rule) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ValidationRules = append([]graphql.Rule{}, rules...)
+	}
+}
+
+// WithMaxDepth rejects the queries nesting fields more than n levels deep.
+func WithMaxDepth(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxDepth = n
+	}
+}
+
+// WithMaxAliases rejects the queries using more than n aliases.
+func WithMaxAliases(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxAliases = n
+	}
+}
+
+// WithMaxRootFields rejects the queries selecting more than n root fields.
+func WithMaxRootFields(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxRootFields = n
+	}
+}
+
+// WithMaxComplexity rejects the queries whose complexity exceeds n. The complexity adds up the costs of the
+// selected fields, which are set with schemabuilder.Cost and multiplied by schemabuilder.CostMultipliers.
+func WithMaxComplexity(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxComplexity = n
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of the successful responses to queries sent with a GET
+// request, for example "public, max-age=60". The responses with errors are never cached.
+func WithCacheControl(directives string) HandlerOption {
+	return func(h *handlerOptions) {
+		h.CacheControl = directives
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of operations in a batch, which is a POST request whose body is an
+// array of operations. The operations of a batch are executed concurrently. It defaults to 10.
+func WithMaxBatchSize(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxBatchSize = n
+	}
+}
+
+// WithQueryCacheSize sets the number of operations kept parsed, by query and operation name, so that repeated
+// queries are not parsed again. It defaults to 1000, and 0 disables the cache.
+func WithQueryCacheSize(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.QueryCacheSize = n
+	}
 }
 
 // HTTPHandler implements the handler required for executing the graphql queries and mutations
 func HTTPHandler(schema *graphql.Schema, opts ...HandlerOption) http.Handler {
-	h := &httpHandler{
-		handler: handler{
-			schema:   schema,
-			executor: &graphql.Executor{},
-		},
-	}
+	o := newHandlerOptions(opts)
 
-	o := handlerOptions{}
-	for _, opt := range opts {
-		opt(&o)
+	h := &httpHandler{
+		handler:       o.handler(schema),
+		cacheControl:  o.CacheControl,
+		maxBatchSize:  o.MaxBatchSize,
+		maxUploadSize: o.MaxUploadSize,
+		uploadMemory:  o.UploadMemory,
+		playground:    o.Playground,
 	}
 
 	prev := h.execute
@@ -39,20 +145,108 @@ func HTTPHandler(schema *graphql.Schema, opts ...HandlerOption) http.Handler {
 	return h
 }
 
+func newHandlerOptions(opts []HandlerOption) handlerOptions {
+	o := handlerOptions{
+		ValidationRules:    graphql.SpecifiedRules,
+		MaxBatchSize:       defaultMaxBatchSize,
+		QueryCacheSize:     defaultQueryCacheSize,
+		MaxUploadSize:      defaultMaxUploadSize,
+		UploadMemory:       defaultUploadMemory,
+		KeepAlive:          defaultKeepAlive,
+		MaxSubscriptions:   defaultMaxSubscriptions,
+		SubscriptionBuffer: defaultSubscriptionBuffer,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// handler returns the handler executing the operations, which is shared by the transports so that the queries,
+// mutations and subscriptions are executed alike.
+func (o *handlerOptions) handler(schema *graphql.Schema) handler {
+	h := handler{
+		schema:     schema,
+		executor:   &graphql.Executor{MaxConcurrency: o.MaxConcurrency, PartialResults: true},
+		rules:      o.rules(),
+		boundRules: o.boundRules(),
+		queryStore: o.QueryStore,
+		trusted:    o.TrustedDocuments,
+	}
+	if h.trusted != nil {
+		if err := h.trusted.validate(schema, append(h.rules, h.boundRules...)); err != nil {
+			panic(err)
+		}
+	}
+	if o.QueryCacheSize > 0 {
+		h.queries = newLRU(o.QueryCacheSize)
+	}
+	return h
+}
+
+// rules returns the validation rules with the rules enforcing the configured limits, but for those depending on
+// the values of the variables.
+func (o *handlerOptions) rules() []graphql.Rule {
+	rules := append([]graphql.Rule{}, o.ValidationRules...)
+	if o.MaxDepth > 0 {
+		rules = append(rules, graphql.MaxDepth(o.MaxDepth))
+	}
+	if o.MaxAliases > 0 {
+		rules = append(rules, graphql.MaxAliases(o.MaxAliases))
+	}
+	if o.MaxRootFields > 0 {
+		rules = append(rules, graphql.MaxRootFields(o.MaxRootFields))
+	}
+	return rules
+}
+
+// boundRules returns the rules depending on the values of the variables, which are checked once the operations
+// are bound: the complexity of a field is multiplied by its arguments, which may be variables.
+func (o *handlerOptions) boundRules() []graphql.Rule {
+	var rules []graphql.Rule
+	if o.MaxComplexity > 0 {
+		rules = append(rules, graphql.MaxComplexity(o.MaxComplexity))
+	}
+	return rules
+}
+
 type handler struct {
 	schema   *graphql.Schema
 	executor *graphql.Executor
+	// rules are checked when the operations are prepared, and boundRules once they are bound.
+	rules      []graphql.Rule
+	boundRules []graphql.Rule
+
+	// queries caches the prepared operations by queryKey, as *preparedQuery.
+	queries *lru
+
+	// queryStore stores the persisted queries.
+	queryStore QueryStore
+
+	// trusted, when set, holds the only documents which can be executed.
+	trusted *TrustedDocuments
 }
 
 type httpHandler struct {
 	handler
 
-	exec HandlerFunc
+	exec         HandlerFunc
+	cacheControl string
+	maxBatchSize int
+
+	maxUploadSize int64
+	uploadMemory  int64
+
+	// playground serves the playground page to browsers.
+	playground bool
 }
 
 type httpPostBody struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Extensions    *httpExtensions        `json:"extensions,omitempty"`
+	DocumentID    string                 `json:"documentId,omitempty"`
 }
 
 type httpResponse struct {
@@ -60,15 +254,28 @@ type httpResponse struct {
 	Errors []*jerrors.Error `json:"errors"`
 }
 
+// newHTTPResponse returns the response to an operation which returned value and err.
+func newHTTPResponse(value interface{}, err error) httpResponse {
+	response := httpResponse{}
+	if multi, ok := err.(*jerrors.MultiError); ok {
+		// Either the query failed validation, or it was executed but some of the fields failed.
+		response.Data = value
+		response.Errors = multi.Errors
+	} else if err != nil {
+		response.Errors = []*jerrors.Error{jerrors.ConvertError(err)}
+	} else {
+		response.Data = value
+	}
+	return response
+}
+
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	writeResponse := func(value interface{}, err error) {
-		response := httpResponse{}
-		if err != nil {
-			response.Errors = []*jerrors.Error{jerrors.ConvertError(err)}
-		} else {
-			response.Data = value
-		}
+	if h.playground && wantsPlayground(r) {
+		servePlayground(w, r.URL.Path, "")
+		return
+	}
 
+	writeJSON := func(response interface{}, cacheable bool) {
 		responseJSON, err := json.Marshal(response)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -77,29 +284,138 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if w.Header().Get("Content-Type") == "" {
 			w.Header().Set("Content-Type", "application/json")
 		}
+
+		if r.Method == http.MethodGet {
+			if !cacheable {
+				w.Header().Set("Cache-Control", "no-store")
+			} else {
+				etag := fmt.Sprintf(`"%x"`, sha256.Sum256(responseJSON))
+				w.Header().Set("ETag", etag)
+				if h.cacheControl != "" {
+					w.Header().Set("Cache-Control", h.cacheControl)
+				}
+				if etagMatches(r.Header.Get("If-None-Match"), etag) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+		}
 		_, _ = w.Write(responseJSON)
 	}
-
-	if r.Method != "POST" {
-		writeResponse(nil, errors.New("request must be a POST"))
-		return
+	writeResponse := func(value interface{}, err error) {
+		response := newHTTPResponse(value, err)
+		writeJSON(response, len(response.Errors) == 0)
 	}
 
-	if r.Body == nil {
-		writeResponse(nil, errors.New("request must include a query"))
-		return
+	switch r.Method {
+	case http.MethodGet:
+		params, err := getParams(r.URL.Query())
+		if err != nil {
+			writeResponse(nil, err)
+			return
+		}
+		writeResponse(h.serve(r.Context(), params, true))
+
+	case http.MethodPost:
+		if r.Body == nil {
+			writeResponse(nil, errors.New("request must include a query"))
+			return
+		}
+
+		var body json.RawMessage
+		var form *multipart.Form
+		if isMultipart(r) {
+			var err error
+			if body, form, err = h.readMultipart(w, r); err != nil {
+				writeResponse(nil, err)
+				return
+			}
+			defer form.RemoveAll()
+		} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeResponse(nil, err)
+			return
+		}
+
+		batched := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+		var batch []httpPostBody
+		if batched {
+			if err := json.Unmarshal(body, &batch); err != nil {
+				writeResponse(nil, err)
+				return
+			}
+		} else {
+			var params httpPostBody
+			if err := json.Unmarshal(body, &params); err != nil {
+				writeResponse(nil, err)
+				return
+			}
+			batch = []httpPostBody{params}
+		}
+
+		if form != nil {
+			closeUploads, err := bindUploads(form, batch, batched)
+			if err != nil {
+				writeResponse(nil, err)
+				return
+			}
+			defer closeUploads()
+		}
+
+		if !batched {
+			writeResponse(h.serve(r.Context(), batch[0], false))
+			return
+		}
+		if len(batch) == 0 {
+			writeResponse(nil, errors.New("batch must include at least one operation"))
+			return
+		}
+		if len(batch) > h.maxBatchSize {
+			writeResponse(nil, fmt.Errorf("batch has %d operations, which exceeds the maximum of %d", len(batch), h.maxBatchSize))
+			return
+		}
+		writeJSON(h.serveBatch(r.Context(), batch), false)
+
+	default:
+		writeResponse(nil, errors.New("request must be a GET or a POST"))
 	}
+}
 
-	var params httpPostBody
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		writeResponse(nil, err)
-		return
+// serveBatch executes the operations of a batch concurrently, and returns their responses in the same order.
+func (h *httpHandler) serveBatch(ctx context.Context, batch []httpPostBody) []httpResponse {
+	responses := make([]httpResponse, len(batch))
+
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = newHTTPResponse(h.serve(ctx, batch[i], false))
+		}(i)
 	}
+	wg.Wait()
+
+	return responses
+}
 
-	query, err := graphql.Parse(params.Query, params.Variables)
+// serve executes an operation. Only queries can be executed when readOnly is set.
+func (h *httpHandler) serve(ctx context.Context, params httpPostBody, readOnly bool) (interface{}, error) {
+	prepared, err := h.prepare(ctx, params)
 	if err != nil {
-		writeResponse(nil, err)
-		return
+		return nil, err
+	}
+	query := prepared.Bind(params.Variables)
+
+	// GET requests may be cached or replayed, so they cannot have side effects.
+	if readOnly && query.Kind != "query" {
+		return nil, fmt.Errorf("%s operations must be sent with a POST request", query.Kind)
+	}
+
+	if err := h.validateBound(query); err != nil {
+		return nil, err
+	}
+
+	if err := graphql.ValidateVariables(h.schema, query, params.Variables); err != nil {
+		return nil, err
 	}
 
 	root := h.schema.Query
@@ -107,15 +423,125 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		root = h.schema.Mutation
 	}
 
-	if err := graphql.ValidateQuery(r.Context(), root, query.SelectionSet); err != nil {
-		writeResponse(nil, err)
-		return
+	if err := graphql.ValidateQuery(ctx, root, query.SelectionSet); err != nil {
+		return nil, err
 	}
 
-	ctx := addVariables(r.Context(), params.Variables)
+	return h.exec(addVariables(ctx, params.Variables), root, query)
+}
+
+// preparedQuery is an operation of the query cache, with the error of its validation.
+type preparedQuery struct {
+	query *graphql.Query
+	err   error
+}
 
-	output, err := h.exec(ctx, root, query)
-	writeResponse(output, err)
+// prepare returns the validated operation of a request, without its variables bound. The operations are cached
+// with the result of their validation, so that repeated queries are not parsed and validated again.
+func (h *handler) prepare(ctx context.Context, params httpPostBody) (*graphql.Query, error) {
+	key := h.queryKey(params)
+	if h.queries != nil && key != "" {
+		if prepared, ok := h.queries.get(key); ok {
+			return prepared.(*preparedQuery).query, prepared.(*preparedQuery).err
+		}
+	}
+
+	document, err := h.parse(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	query, err := document.Prepare(params.OperationName)
+	if err == nil {
+		err = graphql.Validate(h.schema, query, h.rules...)
+	}
+	if err != nil {
+		query = nil
+	}
+
+	if h.queries != nil && key != "" {
+		h.queries.add(key, &preparedQuery{query: query, err: err})
+	}
+	return query, err
+}
+
+// validateBound checks the rules depending on the values of the variables, once an operation is bound.
+func (h *handler) validateBound(query *graphql.Query) error {
+	if len(h.boundRules) == 0 {
+		return nil
+	}
+	return graphql.Validate(h.schema, query, h.boundRules...)
+}
+
+// queryKey returns the key of the operation of a request in the query cache, or an empty key if the operation
+// must not be cached.
+func (h *handler) queryKey(params httpPostBody) string {
+	var persisted *persistedQuery
+	if params.Extensions != nil {
+		persisted = params.Extensions.PersistedQuery
+	}
+
+	var source string
+	switch {
+	case h.trusted != nil:
+		// The trusted documents are referred to by id, and queries sent as text are refused.
+		id := params.DocumentID
+		if id == "" && persisted != nil {
+			id = persisted.Sha256Hash
+		}
+		if id == "" {
+			return ""
+		}
+		source = "id:" + id
+
+	case persisted != nil:
+		// The hash of a query sent along with its hash must be checked before the query is stored.
+		if params.Query != "" || h.queryStore == nil || persisted.Version != 1 {
+			return ""
+		}
+		source = "sha256:" + strings.ToLower(persisted.Sha256Hash)
+
+	default:
+		source = "query:" + params.Query
+	}
+	return source + "\x00" + params.OperationName
+}
+
+// getParams reads the parameters of a GET request from its URL, in which the variables and the extensions are
+// encoded as JSON.
+func getParams(values url.Values) (httpPostBody, error) {
+	params := httpPostBody{
+		Query:         values.Get("query"),
+		OperationName: values.Get("operationName"),
+		DocumentID:    values.Get("documentId"),
+	}
+
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+			return params, fmt.Errorf("variables must be a JSON object: %v", err)
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &params.Extensions); err != nil {
+			return params, fmt.Errorf("extensions must be a JSON object: %v", err)
+		}
+	}
+
+	// The query of a persisted query is only sent when the server does not know it.
+	if params.Query == "" && params.DocumentID == "" && (params.Extensions == nil || params.Extensions.PersistedQuery == nil) {
+		return params, errors.New("request must include a query")
+	}
+	return params, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches etag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *httpHandler) execute(ctx context.Context, root graphql.Type, query *graphql.Query) (interface{}, error) {
@@ -1,13 +1,25 @@
 package jaal_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/kylelemons/godebug/pretty"
 	"go.appointy.com/jaal"
+	"go.appointy.com/jaal/graphql"
 	"go.appointy.com/jaal/schemabuilder"
 )
 
@@ -28,8 +40,8 @@ func testHTTPRequest(req *http.Request) *httptest.ResponseRecorder {
 	return rr
 }
 
-func TestHTTPMustPost(t *testing.T) {
-	req, err := http.NewRequest("GET", "/graphql", nil)
+func TestHTTPMustGetOrPost(t *testing.T) {
+	req, err := http.NewRequest("PUT", "/graphql", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -40,7 +52,7 @@ func TestHTTPMustPost(t *testing.T) {
 		t.Errorf("expected 200, but received %d", rr.Code)
 	}
 
-	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request must be a POST","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request must be a GET or a POST","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
@@ -80,7 +92,7 @@ func TestHTTPMustHaveQuery(t *testing.T) {
 }
 
 func TestHTTPSuccess(t *testing.T) {
-	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: int64) { mirror(value: $value) }", "variables": { "value": 1 }}`))
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: Int) { mirror(value: $value) }", "variables": { "value": 1 }}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -97,7 +109,7 @@ func TestHTTPSuccess(t *testing.T) {
 }
 
 func TestHTTPContentType(t *testing.T) {
-	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: int64) { mirror(value: $value) }", "variables": { "value": 1 }}`))
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: Int) { mirror(value: $value) }", "variables": { "value": 1 }}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -112,3 +124,534 @@ func TestHTTPContentType(t *testing.T) {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
+
+func TestHTTPPartialResults(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	query.FieldFunc("broken", func() (*int64, error) {
+		return nil, errors.New("broken")
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) broken }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild()).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, but received %d", rr.Code)
+	}
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"broken":null,"mirror":-1},"errors":[{"message":"broken","extensions":{"code":"Unknown"},"paths":["broken"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPOperationName(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query A { mirror(value: 1) } query B { mirror(value: 2) }", "operationName": "B"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), "{\"data\":{\"mirror\":-2},\"errors\":null}"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPValidationErrors(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(valu: 1) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"unknown argument \"valu\" on field \"mirror\"","extensions":{"code":"InvalidArgument"},"paths":[],"locations":[{"line":1,"column":10}]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPQueryLimits(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	}, schemabuilder.Cost(10))
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithMaxRootFields(3), jaal.WithMaxComplexity(25))
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ a: mirror(value: 1) b: mirror(value: 2) c: mirror(value: 3) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"query has complexity 30, which exceeds the maximum complexity of 25","extensions":{"code":"ResourceExhausted"},"paths":[],"locations":[{"line":1,"column":1}]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPGet(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	mutation := schema.Mutation()
+	mutation.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithCacheControl("public, max-age=60"))
+
+	params := url.Values{}
+	params.Set("query", "query TestQuery($value: Int) { mirror(value: $value) }")
+	params.Set("variables", `{"value": 1}`)
+	req, err := http.NewRequest("GET", "/graphql?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(rr.HeaderMap.Get("Cache-Control"), "public, max-age=60"); diff != "" {
+		t.Errorf("expected Cache-Control to match, but received %s", diff)
+	}
+	etag := rr.HeaderMap.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	// A request with a matching ETag is not sent the response again.
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304, but received %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected an empty body, but received %s", rr.Body.String())
+	}
+
+	// Mutations cannot be sent with a GET request.
+	params = url.Values{}
+	params.Set("query", "mutation { mirror(value: 1) }")
+	req, err = http.NewRequest("GET", "/graphql?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"mutation operations must be sent with a POST request","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(rr.HeaderMap.Get("Cache-Control"), "no-store"); diff != "" {
+		t.Errorf("expected Cache-Control to match, but received %s", diff)
+	}
+}
+
+func TestHTTPBatch(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithMaxBatchSize(2))
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`[
+		{"query": "query TestQuery($value: Int) { mirror(value: $value) }", "variables": { "value": 1 }},
+		{"query": "{ mirror(valu: 2) }"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `[{"data":{"mirror":-1},"errors":null},{"data":null,"errors":[{"message":"unknown argument \"valu\" on field \"mirror\"","extensions":{"code":"InvalidArgument"},"paths":[],"locations":[{"line":1,"column":10}]}]}]`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`[{"query": "{ a: mirror(value: 1) }"}, {"query": "{ b: mirror(value: 2) }"}, {"query": "{ c: mirror(value: 3) }"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"batch has 3 operations, which exceeds the maximum of 2","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPPersistedQueries(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithPersistedQueries(nil))
+
+	sum := sha256.Sum256([]byte("{ mirror(value: 1) }"))
+	hash := hex.EncodeToString(sum[:])
+
+	for _, c := range []struct {
+		body     string
+		expected string
+	}{
+		{
+			body:     `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}}`,
+			expected: `{"data":null,"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"},"paths":[]}]}`,
+		},
+		{
+			body:     `{"query": "{ mirror(value: 2) }", "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}}`,
+			expected: `{"data":null,"errors":[{"message":"provided sha does not match query","extensions":{"code":"PERSISTED_QUERY_HASH_MISMATCH"},"paths":[]}]}`,
+		},
+		{
+			body:     `{"query": "{ mirror(value: 1) }", "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}}`,
+			expected: `{"data":{"mirror":-1},"errors":null}`,
+		},
+		{
+			body:     `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + hash + `"}}}`,
+			expected: `{"data":{"mirror":-1},"errors":null}`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if diff := pretty.Compare(rr.Body.String(), c.expected); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+}
+
+func TestHTTPTrustedDocuments(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+	builtSchema := schema.MustBuild()
+
+	if _, err := jaal.NewTrustedDocuments(builtSchema, map[string]string{"broken": "{ mirror(valu: 1) }"}); err == nil {
+		t.Error("expected an invalid document to be refused")
+	}
+
+	file, err := ioutil.TempFile("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(`{"mirror": "query Mirror($value: Int) { mirror(value: $value) }"}`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	documents, err := jaal.LoadTrustedDocuments(builtSchema, file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := jaal.HTTPHandler(builtSchema, jaal.WithTrustedDocuments(documents))
+
+	// The documents are validated with the limits of the handler they are attached to.
+	limited, err := jaal.NewTrustedDocuments(builtSchema, map[string]string{"both": "{ a: mirror(value: 1) b: mirror(value: 2) }"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a document over the limits of the handler to be refused")
+			}
+		}()
+		jaal.HTTPHandler(builtSchema, jaal.WithTrustedDocuments(limited), jaal.WithMaxRootFields(1))
+	}()
+
+	for _, c := range []struct {
+		body     string
+		expected string
+	}{
+		{
+			body:     `{"documentId": "mirror", "variables": {"value": 1}}`,
+			expected: `{"data":{"mirror":-1},"errors":null}`,
+		},
+		{
+			body:     `{"documentId": "unknown"}`,
+			expected: `{"data":null,"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"},"paths":[]}]}`,
+		},
+		{
+			body:     `{"query": "{ mirror(value: 1) }"}`,
+			expected: `{"data":null,"errors":[{"message":"only trusted documents can be executed","extensions":{"code":"PermissionDenied"},"paths":[]}]}`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if diff := pretty.Compare(rr.Body.String(), c.expected); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+}
+
+func TestHTTPQueryCache(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithQueryCacheSize(1))
+
+	// The same query is bound to different variables by concurrent requests.
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(value int) {
+			defer wg.Done()
+
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(fmt.Sprintf(`{"query": "query TestQuery($value: Int) { mirror(value: $value) }", "variables": { "value": %d }}`, value)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if diff := pretty.Compare(rr.Body.String(), fmt.Sprintf(`{"data":{"mirror":%d},"errors":null}`, -value)); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestHTTPQueryCacheValidation(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	// The operations are validated once, along with their errors.
+	var validations int32
+	counter := func(c *graphql.ValidationContext) {
+		atomic.AddInt32(&validations, 1)
+	}
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithValidationRules(append(graphql.SpecifiedRules, counter)...), jaal.WithMaxComplexity(10))
+
+	for _, c := range []struct {
+		body     string
+		expected string
+	}{
+		{
+			body:     `{"query": "query Q($value: Int) { mirror(value: $value) }", "variables": {"value": 1}}`,
+			expected: `{"data":{"mirror":-1},"errors":null}`,
+		},
+		{
+			body:     `{"query": "query Q($value: Int) { mirror(value: $value) }", "variables": {"value": 2}}`,
+			expected: `{"data":{"mirror":-2},"errors":null}`,
+		},
+		{
+			body:     `{"query": "{ mirror(valu: 1) }"}`,
+			expected: `{"data":null,"errors":[{"message":"unknown argument \"valu\" on field \"mirror\"","extensions":{"code":"InvalidArgument"},"paths":[],"locations":[{"line":1,"column":10}]}]}`,
+		},
+		{
+			body:     `{"query": "{ mirror(valu: 1) }"}`,
+			expected: `{"data":null,"errors":[{"message":"unknown argument \"valu\" on field \"mirror\"","extensions":{"code":"InvalidArgument"},"paths":[],"locations":[{"line":1,"column":10}]}]}`,
+		},
+	} {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if diff := pretty.Compare(rr.Body.String(), c.expected); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+	if validations != 2 {
+		t.Errorf("expected the operations to be validated twice, but they were validated %d times", validations)
+	}
+}
+
+func TestHTTPUpload(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	mutation := schema.Mutation()
+	mutation.FieldFunc("upload", func(args struct{ Files []schemabuilder.Upload }) ([]string, error) {
+		var contents []string
+		for _, file := range args.Files {
+			content, err := ioutil.ReadAll(file)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, fmt.Sprintf("%s %s %d %s", file.Filename, file.ContentType, file.Size, content))
+		}
+		return contents, nil
+	})
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithMaxUploadSize(1024), jaal.WithUploadMemory(4))
+
+	newRequest := func(operations, fileMap string, files map[string]string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if err := writer.WriteField("operations", operations); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.WriteField("map", fileMap); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			part, err := writer.CreateFormFile(name, name+".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := part.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("POST", "/graphql", &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	for _, c := range []struct {
+		req      *http.Request
+		expected string
+	}{
+		{
+			req: newRequest(
+				`{"query": "mutation ($files: [Upload!]) { upload(files: $files) }", "variables": {"files": [null, null]}}`,
+				`{"a": ["variables.files.0"], "b": ["variables.files.1"]}`,
+				map[string]string{"a": "first", "b": "second"},
+			),
+			expected: `{"data":{"upload":["a.txt application/octet-stream 5 first","b.txt application/octet-stream 6 second"]},"errors":null}`,
+		},
+		{
+			req: newRequest(
+				`[{"query": "mutation ($files: [Upload!]) { upload(files: $files) }", "variables": {"files": [null]}}, {"query": "{ mirror(value: 1) }"}]`,
+				`{"a": ["0.variables.files.0"]}`,
+				map[string]string{"a": "first"},
+			),
+			expected: `[{"data":{"upload":["a.txt application/octet-stream 5 first"]},"errors":null},{"data":{"mirror":-1},"errors":null}]`,
+		},
+		{
+			req: newRequest(
+				`{"query": "mutation ($files: [Upload!]) { upload(files: $files) }", "variables": {"files": [null]}}`,
+				`{"a": ["variables.other.0"]}`,
+				map[string]string{"a": "first"},
+			),
+			expected: `{"data":null,"errors":[{"message":"invalid upload path variables.other.0","extensions":{"code":"Unknown"},"paths":[]}]}`,
+		},
+		{
+			req: newRequest(
+				`{"query": "mutation ($files: [Upload!]) { upload(files: $files) }", "variables": {"files": [null]}}`,
+				`{"a": ["variables.files.0"]}`,
+				map[string]string{"a": strings.Repeat("a", 2048)},
+			),
+			expected: `{"data":null,"errors":[{"message":"failed to read multipart request: http: request body too large","extensions":{"code":"Unknown"},"paths":[]}]}`,
+		},
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, c.req)
+
+		if diff := pretty.Compare(rr.Body.String(), c.expected); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+}
+
+func TestHTTPPlayground(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	rr := httptest.NewRecorder()
+	jaal.PlaygroundHandler("/graphql", "/subscriptions").ServeHTTP(rr, httptest.NewRequest("GET", "/playground", nil))
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("expected an html page, but received %s", contentType)
+	}
+	for _, s := range []string{`var endpoint = "/graphql"`, `var subscriptionEndpoint = "/subscriptions"`} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("expected the page to contain %s", s)
+		}
+	}
+
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithPlayground())
+
+	req := httptest.NewRequest("GET", "/graphql", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), `var endpoint = "/graphql"`) {
+		t.Errorf("expected the playground page, but received %s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape("{ mirror(value: 1) }"), nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
@@ -265,8 +265,9 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		case *graphql.InputObject:
 			for name, f := range t.InputFields {
 				fields = append(fields, InputValue{
-					Name: name,
-					Type: Type{Inner: f},
+					Name:        name,
+					Description: t.FieldDescriptions[name],
+					Type:        Type{Inner: f},
 				})
 			}
 		}
@@ -279,41 +280,20 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		IncludeDeprecated *bool
 	}) []field {
 		var fields []field
+		includeDeprecated := args.IncludeDeprecated != nil && *args.IncludeDeprecated
 
 		switch t := t.Inner.(type) {
 		case *graphql.Object:
 			for name, f := range t.Fields {
-				var args []InputValue
-				for name, a := range f.Args {
-					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
-					})
+				if f.DeprecationReason == "" || includeDeprecated {
+					fields = append(fields, makeField(name, f))
 				}
-				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
-
-				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
-				})
 			}
 		case *graphql.Interface:
 			for name, f := range t.Fields {
-				var args []InputValue
-				for name, a := range f.Args {
-					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
-					})
+				if f.DeprecationReason == "" || includeDeprecated {
+					fields = append(fields, makeField(name, f))
 				}
-				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
-
-				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
-				})
 			}
 		}
 		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
@@ -338,11 +318,20 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 
 		switch t := t.Inner.(type) {
 		case *graphql.Enum:
+			includeDeprecated := args.IncludeDeprecated != nil && *args.IncludeDeprecated
 			var enumVals []EnumValue
 			for k, v := range t.ReverseMap {
-				val := fmt.Sprintf("%v", k)
+				reason := t.ValueDeprecationReasons[v]
+				if reason != "" && !includeDeprecated {
+					continue
+				}
+
+				description, ok := t.ValueDescriptions[v]
+				if !ok {
+					description = fmt.Sprintf("%v", k)
+				}
 				enumVals = append(enumVals,
-					EnumValue{Name: v, Description: val, IsDeprecated: false, DeprecationReason: ""})
+					EnumValue{Name: v, Description: description, IsDeprecated: reason != "", DeprecationReason: reason})
 			}
 			sort.Slice(enumVals, func(i, j int) bool { return enumVals[i].Name < enumVals[j].Name })
 			return enumVals
@@ -360,6 +349,28 @@ type field struct {
 	DeprecationReason string
 }
 
+// makeField describes the field f named name.
+func makeField(name string, f *graphql.Field) field {
+	var args []InputValue
+	for name, a := range f.Args {
+		args = append(args, InputValue{
+			Name:        name,
+			Description: f.ArgDescriptions[name],
+			Type:        Type{Inner: a},
+		})
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
+
+	return field{
+		Name:              name,
+		Description:       f.Description,
+		Type:              Type{Inner: f.Type},
+		Args:              args,
+		IsDeprecated:      f.DeprecationReason != "",
+		DeprecationReason: f.DeprecationReason,
+	}
+}
+
 func (s *introspection) registerField(schema *schemabuilder.Schema) {
 	obj := schema.Object("__Field", field{})
 	obj.FieldFunc("name", func(in field) string {
@@ -574,6 +585,10 @@ func ComputeSchemaJSON(schemaBuilderSchema schemabuilder.Schema) ([]byte, error)
 		return nil, err
 	}
 
+	if err := graphql.Validate(schema, query, graphql.SpecifiedRules...); err != nil {
+		return nil, err
+	}
+
 	if err := graphql.ValidateQuery(context.Background(), schema.Query, query.SelectionSet); err != nil {
 		return nil, err
 	}
@@ -139,6 +139,65 @@ func TestComputeSchemaJSON(t *testing.T) {
 	json.Unmarshal(actualBytes, &actual)
 }
 
+func TestIntrospectionDescriptions(t *testing.T) {
+	type color int32
+
+	builder := schemabuilder.NewSchema()
+	builder.Enum(color(0), map[string]color{
+		"RED":   color(0),
+		"GREEN": color(1),
+	}, schemabuilder.EnumValueDescription("RED", "The color of fire."), schemabuilder.EnumValueDeprecated("GREEN", "use RED"))
+
+	query := builder.Query()
+	query.FieldFunc("paint", func(args struct {
+		Color color `description:"The color to paint with."`
+	}) color {
+		return args.Color
+	}, schemabuilder.Description("Paints with a color."))
+	query.FieldFunc("oldPaint", func() color {
+		return color(0)
+	}, schemabuilder.Deprecated("use paint"))
+
+	schema := builder.MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+
+	source := `{
+		__type(name: "Query") {
+			fields(includeDeprecated: true) { name description isDeprecated deprecationReason args { name description } }
+			current: fields { name }
+		}
+		color: __type(name: "color") {
+			enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+			current: enumValues { name }
+		}
+	}`
+	q, err := graphql.Parse(source, nil)
+	require.NoError(t, err)
+	require.NoError(t, graphql.ValidateQuery(context.Background(), schema.Query, q.SelectionSet))
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), schema.Query, nil, q)
+	require.NoError(t, err)
+
+	actual, _ := json.Marshal(result)
+	require.JSONEq(t, `{
+		"__type": {
+			"fields": [
+				{"name": "oldPaint", "description": "", "isDeprecated": true, "deprecationReason": "use paint", "args": []},
+				{"name": "paint", "description": "Paints with a color.", "isDeprecated": false, "deprecationReason": "", "args": [{"name": "color", "description": "The color to paint with."}]}
+			],
+			"current": [{"name": "paint"}]
+		},
+		"color": {
+			"enumValues": [
+				{"name": "GREEN", "description": "1", "isDeprecated": true, "deprecationReason": "use RED"},
+				{"name": "RED", "description": "The color of fire.", "isDeprecated": false, "deprecationReason": ""}
+			],
+			"current": [{"name": "RED"}]
+		}
+	}`, string(actual))
+}
+
 func TestIntrospectionForInterface(t *testing.T) {
 	s := node{
 		customers: []Customer{
@@ -11,6 +11,13 @@ type Error struct {
 	Message    string     `json:"message"`
 	Extensions *Extension `json:"extensions"`
 	Paths      []string   `json:"paths"`
+	Locations  []Location `json:"locations,omitempty"`
+}
+
+// Location is a position in the source of a query, which is reported for errors found while validating it
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
 }
 
 // Extension contains extra fields in the error
@@ -35,7 +42,8 @@ func NestErrorPaths(e error, path string) error {
 		Extensions: &Extension{
 			Code: err.Extensions.Code,
 		},
-		Message: err.Message,
+		Message:   err.Message,
+		Locations: err.Locations,
 	}
 	newError.Paths = append(newError.Paths, err.Paths...)
 
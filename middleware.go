@@ -4,6 +4,8 @@ import "context"
 
 import "go.appointy.com/jaal/graphql"
 
+// HandlerFunc executes a query. When some of the fields of the query fail, it returns the partial
+// data along with a *jerrors.MultiError holding the errors of those fields.
 type HandlerFunc func(context.Context, graphql.Type, *graphql.Query) (interface{}, error)
 
 type MiddlewareFunc func(HandlerFunc) HandlerFunc
@@ -0,0 +1,118 @@
+package jaal
+
+// This file contains the automatic persisted queries, which let clients send the SHA-256 hash of a query
+// instead of the query itself. A client first sends only the hash, and sends the query along with its hash when
+// the server answers that the query was not found.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+)
+
+// defaultPersistedQueries is the number of queries kept by the default QueryStore.
+const defaultPersistedQueries = 1000
+
+// QueryStore stores the persisted queries by the hex encoded SHA-256 hash of their source.
+type QueryStore interface {
+	// Get returns the query with the hash, and whether it was found.
+	Get(ctx context.Context, hash string) (string, bool)
+	// Put stores a query with its hash, which was checked to match the query.
+	Put(ctx context.Context, hash string, query string)
+}
+
+// LRUQueryStore is a QueryStore keeping the most recently used queries in memory.
+type LRUQueryStore struct {
+	cache *lru
+}
+
+// NewLRUQueryStore returns a QueryStore keeping at most size queries in memory.
+func NewLRUQueryStore(size int) *LRUQueryStore {
+	return &LRUQueryStore{cache: newLRU(size)}
+}
+
+// Get implements QueryStore.
+func (s *LRUQueryStore) Get(ctx context.Context, hash string) (string, bool) {
+	query, ok := s.cache.get(hash)
+	if !ok {
+		return "", false
+	}
+	return query.(string), true
+}
+
+// Put implements QueryStore.
+func (s *LRUQueryStore) Put(ctx context.Context, hash string, query string) {
+	s.cache.add(hash, query)
+}
+
+// WithPersistedQueries enables the automatic persisted queries, which are stored in store. A nil store keeps
+// the 1000 most recently used queries in memory.
+func WithPersistedQueries(store QueryStore) HandlerOption {
+	return func(h *handlerOptions) {
+		if store == nil {
+			store = NewLRUQueryStore(defaultPersistedQueries)
+		}
+		h.QueryStore = store
+	}
+}
+
+type httpExtensions struct {
+	PersistedQuery *persistedQuery `json:"persistedQuery,omitempty"`
+}
+
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+// The errors are those expected by the clients implementing automatic persisted queries.
+func persistedQueryError(message, code string) error {
+	return &jerrors.Error{
+		Message:    message,
+		Extensions: &jerrors.Extension{Code: code},
+		Paths:      []string{},
+	}
+}
+
+// parse parses the query of a request, which may be a persisted query or a trusted document.
+func (h *handler) parse(ctx context.Context, params httpPostBody) (*graphql.Document, error) {
+	if h.trusted != nil {
+		return h.trusted.document(params)
+	}
+
+	if params.Extensions == nil || params.Extensions.PersistedQuery == nil {
+		return graphql.ParseDocument(params.Query)
+	}
+
+	if h.queryStore == nil {
+		return nil, persistedQueryError("PersistedQueryNotSupported", "PERSISTED_QUERY_NOT_SUPPORTED")
+	}
+	if params.Extensions.PersistedQuery.Version != 1 {
+		return nil, persistedQueryError("unsupported persisted query version", "PERSISTED_QUERY_NOT_SUPPORTED")
+	}
+	hash := strings.ToLower(params.Extensions.PersistedQuery.Sha256Hash)
+
+	if params.Query == "" {
+		query, ok := h.queryStore.Get(ctx, hash)
+		if !ok {
+			return nil, persistedQueryError("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND")
+		}
+		return graphql.ParseDocument(query)
+	}
+
+	sum := sha256.Sum256([]byte(params.Query))
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, persistedQueryError("provided sha does not match query", "PERSISTED_QUERY_HASH_MISMATCH")
+	}
+
+	document, err := graphql.ParseDocument(params.Query)
+	if err != nil {
+		return nil, err
+	}
+	h.queryStore.Put(ctx, hash, params.Query)
+	return document, nil
+}
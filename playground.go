@@ -0,0 +1,246 @@
+package jaal
+
+// This file contains the playground, a page to write and run operations from a browser. The page is
+// self-contained: it loads no script or stylesheet from other hosts, so it also works offline. It is a minimal
+// editor of its own, not GraphiQL: it has no schema explorer, autocompletion or validation as you type.
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// PlaygroundHandler returns a handler serving the playground page, which sends the queries and mutations to the
+// HTTPHandler at endpoint, and the subscriptions to the HTTPSubHandler at subscriptionEndpoint. Subscriptions
+// are disabled when subscriptionEndpoint is empty.
+func PlaygroundHandler(endpoint, subscriptionEndpoint string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servePlayground(w, endpoint, subscriptionEndpoint)
+	})
+}
+
+// WithPlayground serves the playground page from the handler itself, to the GET requests without a query
+// which accept text/html, such as those of a browser.
+func WithPlayground() HandlerOption {
+	return func(h *handlerOptions) {
+		h.Playground = true
+	}
+}
+
+// wantsPlayground checks if a request is a browser asking for the playground page.
+func wantsPlayground(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return false
+	}
+
+	params := r.URL.Query()
+	for _, key := range []string{"query", "documentId", "extensions"} {
+		if _, ok := params[key]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+func servePlayground(w http.ResponseWriter, endpoint, subscriptionEndpoint string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := playgroundTemplate.Execute(w, struct {
+		Endpoint             string
+		SubscriptionEndpoint string
+	}{endpoint, subscriptionEndpoint}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GraphQL Playground</title>
+<style>
+* { box-sizing: border-box; }
+body { margin: 0; height: 100vh; display: flex; flex-direction: column; font: 14px sans-serif; color: #222; }
+header { display: flex; align-items: center; gap: 12px; padding: 8px 12px; background: #f3f3f3; border-bottom: 1px solid #ddd; }
+header h1 { font-size: 16px; margin: 0; }
+header .endpoint { color: #777; font-family: monospace; }
+button { padding: 4px 14px; cursor: pointer; }
+main { flex: 1; display: flex; min-height: 0; }
+section { flex: 1; display: flex; flex-direction: column; min-width: 0; border-right: 1px solid #ddd; }
+label { padding: 4px 8px; font-size: 12px; color: #777; background: #fafafa; border-bottom: 1px solid #eee; }
+textarea, pre { flex: 1; margin: 0; padding: 8px; border: 0; resize: none; outline: none; overflow: auto; font: 13px monospace; tab-size: 2; }
+#variables, #headers { flex: 0 0 20%; border-top: 1px solid #ddd; }
+#docs { flex: 0 0 22%; overflow: auto; padding: 8px; font: 13px monospace; border-right: 0; }
+#docs h2 { font: bold 13px sans-serif; margin: 12px 0 4px; }
+#docs div { padding-left: 12px; white-space: nowrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>GraphQL Playground</h1>
+<button id="run" title="Ctrl-Enter">Run</button>
+<button id="stop" hidden>Stop</button>
+<span class="endpoint" id="endpoint"></span>
+</header>
+<main>
+<section>
+<label>Query</label>
+<textarea id="query" spellcheck="false">{
+  __typename
+}</textarea>
+<label>Variables</label>
+<textarea id="variables" spellcheck="false">{}</textarea>
+<label>Headers</label>
+<textarea id="headers" spellcheck="false">{}</textarea>
+</section>
+<section>
+<label>Response</label>
+<pre id="response"></pre>
+</section>
+<section id="docs"></section>
+</main>
+<script>
+(function() {
+  var endpoint = {{.Endpoint}} || window.location.pathname;
+  var subscriptionEndpoint = {{.SubscriptionEndpoint}};
+  var socket = null;
+
+  var $ = function(id) { return document.getElementById(id); };
+  $("endpoint").textContent = endpoint;
+
+  var storage = window.localStorage;
+  ["query", "variables", "headers"].forEach(function(id) {
+    var key = "jaal-playground-" + id;
+    if (storage && storage.getItem(key) !== null) {
+      $(id).value = storage.getItem(key);
+    }
+    $(id).addEventListener("input", function() {
+      if (storage) {
+        storage.setItem(key, $(id).value);
+      }
+    });
+  });
+
+  function parseJSON(id) {
+    var text = $(id).value.trim();
+    return text ? JSON.parse(text) : {};
+  }
+
+  function show(value) {
+    $("response").textContent = typeof value === "string" ? value : JSON.stringify(value, null, 2);
+  }
+
+  function post(body) {
+    var headers = parseJSON("headers");
+    headers["Content-Type"] = "application/json";
+    return fetch(endpoint, {method: "POST", headers: headers, body: JSON.stringify(body), credentials: "same-origin"})
+      .then(function(response) { return response.json(); });
+  }
+
+  function stop() {
+    if (socket) {
+      socket.close();
+      socket = null;
+    }
+    $("stop").hidden = true;
+  }
+
+  function subscribe(body) {
+    if (!subscriptionEndpoint) {
+      show("Subscriptions are not enabled.");
+      return;
+    }
+    var url = new URL(subscriptionEndpoint, window.location.href);
+    url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+
+    var results = [];
+    socket = new WebSocket(url.href, "graphql-ws");
+    socket.onopen = function() {
+      socket.send(JSON.stringify({type: "connection_init", payload: parseJSON("headers")}));
+      socket.send(JSON.stringify({type: "start", id: "1", payload: body}));
+      $("stop").hidden = false;
+      show("Waiting for events...");
+    };
+    socket.onmessage = function(event) {
+      var message = JSON.parse(event.data);
+      if (message.type === "data" || message.type === "error" || message.type === "connection_error") {
+        results.unshift(message.payload);
+        show(results);
+      }
+      if (message.type === "complete" || message.type === "connection_error") {
+        stop();
+      }
+    };
+    socket.onerror = function() { show("The connection to " + url.href + " failed."); };
+    socket.onclose = function() { $("stop").hidden = true; };
+  }
+
+  function run() {
+    stop();
+    var body;
+    try {
+      body = {query: $("query").value, variables: parseJSON("variables")};
+      parseJSON("headers");
+    } catch (e) {
+      show("Invalid JSON: " + e.message);
+      return;
+    }
+
+    if (/^\s*subscription\b/m.test(body.query.replace(/#.*$/gm, ""))) {
+      subscribe(body);
+      return;
+    }
+    show("Loading...");
+    post(body).then(show, function(e) { show(e.message); });
+  }
+
+  function typeName(type) {
+    if (type.kind === "NON_NULL") {
+      return typeName(type.ofType) + "!";
+    }
+    if (type.kind === "LIST") {
+      return "[" + typeName(type.ofType) + "]";
+    }
+    return type.name;
+  }
+
+  function loadDocs() {
+    var query = "{ __schema { types { name kind fields { name args { name type { ...TypeRef } } type { ...TypeRef } } " +
+      "inputFields { name type { ...TypeRef } } enumValues { name } } } } " +
+      "fragment TypeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }";
+    post({query: query}).then(function(response) {
+      if (!response.data) {
+        return;
+      }
+      var docs = $("docs");
+      response.data.__schema.types.forEach(function(type) {
+        if (type.name.indexOf("__") === 0) {
+          return;
+        }
+        var title = document.createElement("h2");
+        title.textContent = type.kind.toLowerCase() + " " + type.name;
+        docs.appendChild(title);
+        (type.fields || type.inputFields || type.enumValues || []).forEach(function(field) {
+          var line = document.createElement("div");
+          var args = (field.args || []).map(function(arg) { return arg.name + ": " + typeName(arg.type); });
+          line.textContent = field.name + (args.length ? "(" + args.join(", ") + ")" : "") +
+            (field.type ? ": " + typeName(field.type) : "");
+          docs.appendChild(line);
+        });
+      });
+    }, function() {});
+  }
+
+  $("run").addEventListener("click", run);
+  $("stop").addEventListener("click", stop);
+  document.addEventListener("keydown", function(event) {
+    if ((event.ctrlKey || event.metaKey) && event.key === "Enter") {
+      event.preventDefault();
+      run();
+    }
+  });
+  loadDocs();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,141 @@
+package jaal
+
+// This file contains the queues of the subscribers, which keep a slow client from holding back the delivery of
+// the events to the others: the events are queued without blocking, and the overflow policy decides what happens
+// to those of a full queue.
+
+import "sync/atomic"
+
+// defaultSubscriptionBuffer is the number of events queued for a subscription, unless set with
+// WithSubscriptionBuffer.
+const defaultSubscriptionBuffer = 64
+
+// OverflowPolicy decides what happens to the events of a subscription whose queue is full.
+type OverflowPolicy int
+
+const (
+	// DropOldest drops the oldest event of the queue to make room for the new one.
+	DropOldest OverflowPolicy = iota
+	// DropNewest drops the new event.
+	DropNewest
+	// Disconnect completes the subscriptions of the slow client and closes its connection, so that it reconnects.
+	Disconnect
+)
+
+// WithSubscriptionBuffer sets the number of events queued for every subscription while its client is busy. It
+// defaults to 64, and is at least 1: the values below are raised to 1.
+func WithSubscriptionBuffer(n int) HandlerOption {
+	if n < 1 {
+		n = 1
+	}
+	return func(h *handlerOptions) {
+		h.SubscriptionBuffer = n
+	}
+}
+
+// WithOverflowPolicy sets what happens to the events of a subscription whose queue is full. It defaults to
+// DropOldest.
+func WithOverflowPolicy(p OverflowPolicy) HandlerOption {
+	return func(h *handlerOptions) {
+		h.OverflowPolicy = p
+	}
+}
+
+// SubscriptionMetrics describes the subscriptions of a SubHandler and the state of their queues.
+type SubscriptionMetrics struct {
+	Connections   int `json:"connections"`
+	Subscriptions int `json:"subscriptions"`
+	// QueuedEvents is the number of events waiting in the queues, and MaxQueueDepth the number waiting in the
+	// fullest one.
+	QueuedEvents  int `json:"queuedEvents"`
+	MaxQueueDepth int `json:"maxQueueDepth"`
+	// DroppedEvents and SlowConsumers count the events dropped and the connections closed by the overflow policy
+	// since the handler was created.
+	DroppedEvents uint64 `json:"droppedEvents"`
+	SlowConsumers uint64 `json:"slowConsumers"`
+}
+
+// Metrics returns the metrics of the subscriptions, to be exported for monitoring.
+func (h *SubHandler) Metrics() SubscriptionMetrics {
+	metrics := SubscriptionMetrics{
+		DroppedEvents: atomic.LoadUint64(&h.sessions.dropped),
+		SlowConsumers: atomic.LoadUint64(&h.sessions.slowConsumers),
+	}
+	for _, conn := range h.Connections() {
+		metrics.Connections++
+		for _, sub := range conn.Subscriptions {
+			metrics.Subscriptions++
+			metrics.QueuedEvents += sub.QueueDepth
+			if sub.QueueDepth > metrics.MaxQueueDepth {
+				metrics.MaxQueueDepth = sub.QueueDepth
+			}
+		}
+	}
+	return metrics
+}
+
+// newSubscriber returns a subscriber of the root fields, which only queues the events accepted by filter, and
+// calls overflow when it is too slow with the Disconnect policy.
+func (h *SubHandler) newSubscriber(fields []string, filter func(*Event) bool, overflow func()) *subscriber {
+	return &subscriber{
+		events:   make(chan *Event, h.bufferSize),
+		fields:   fields,
+		filter:   filter,
+		done:     make(chan struct{}),
+		policy:   h.overflowPolicy,
+		overflow: overflow,
+	}
+}
+
+// push queues an event accepted by the filter of the subscriber without blocking, and applies the overflow
+// policy when the queue is full.
+func (s *subscriber) push(evt *Event, ss *sessions) {
+	if s.filter != nil && !s.filter(evt) {
+		return
+	}
+
+	select {
+	case s.events <- evt:
+		return
+	case <-s.done:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case DropOldest:
+		// The subscriber may take an event meanwhile, which leaves room for the new one.
+		select {
+		case <-s.events:
+			s.drop(ss)
+		default:
+		}
+		select {
+		case s.events <- evt:
+			return
+		default:
+		}
+	case Disconnect:
+		s.overflowed.Do(func() {
+			atomic.AddUint64(&ss.slowConsumers, 1)
+			go s.overflow()
+		})
+	}
+	s.drop(ss)
+}
+
+func (s *subscriber) drop(ss *sessions) {
+	atomic.AddUint64(&s.dropped, 1)
+	atomic.AddUint64(&ss.dropped, 1)
+}
+
+// queueDepth returns the number of events queued by subscribers, and the number they dropped.
+func queueDepth(subscribers []*subscriber) (int, uint64) {
+	var depth int
+	var dropped uint64
+	for _, s := range subscribers {
+		depth += len(s.events)
+		dropped += atomic.LoadUint64(&s.dropped)
+	}
+	return depth, dropped
+}
@@ -0,0 +1,100 @@
+package jaal
+
+// This file contains the registry of the websocket connections and event streams served by a SubHandler, which
+// scopes their subscriptions and lists them for debugging.
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// defaultMaxSubscriptions is the number of subscriptions a connection may run at once, unless set with
+// WithMaxSubscriptions.
+const defaultMaxSubscriptions = 100
+
+// WithMaxSubscriptions sets the number of subscriptions a websocket connection may run at once. The
+// subscriptions started beyond it are answered with an error. It defaults to 100, and 0 disables the limit.
+func WithMaxSubscriptions(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxSubscriptions = n
+	}
+}
+
+// connections numbers the websocket connections and event streams, which scope the ids of their subscriptions.
+var connections uint64
+
+// ConnectionInfo describes a websocket connection or an event stream being served.
+type ConnectionInfo struct {
+	ID string `json:"id"`
+	// Protocol is the websocket subprotocol of the connection, or sse for an event stream.
+	Protocol      string             `json:"protocol"`
+	RemoteAddr    string             `json:"remoteAddr"`
+	Started       time.Time          `json:"started"`
+	Subscriptions []SubscriptionInfo `json:"subscriptions"`
+}
+
+// SubscriptionInfo describes an active subscription of a connection. The ids are chosen by the clients, and
+// event streams carry a single subscription with the id of the stream.
+type SubscriptionInfo struct {
+	ID            string    `json:"id"`
+	OperationName string    `json:"operationName,omitempty"`
+	Fields        []string  `json:"fields"`
+	Started       time.Time `json:"started"`
+	// QueueDepth is the number of events waiting to be executed, and Dropped the number dropped by the overflow
+	// policy.
+	QueueDepth int    `json:"queueDepth"`
+	Dropped    uint64 `json:"dropped"`
+}
+
+// Connections lists the websocket connections and event streams being served with their active subscriptions,
+// from the oldest to the newest.
+func (h *SubHandler) Connections() []ConnectionInfo {
+	h.mu.Lock()
+	conns := make([]*webConn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		infos = append(infos, conn.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Started.Before(infos[j].Started)
+	})
+	return infos
+}
+
+func newWebConn(id string, r *http.Request, protocol string) *webConn {
+	return &webConn{
+		id:         id,
+		transport:  protocol,
+		remoteAddr: r.RemoteAddr,
+		started:    time.Now(),
+		subs:       map[string]*connSubscription{},
+	}
+}
+
+func (w *webConn) info() ConnectionInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	subs := make([]SubscriptionInfo, 0, len(w.subs))
+	for _, sub := range w.subs {
+		info := sub.info
+		info.QueueDepth, info.Dropped = queueDepth(sub.subscribers)
+		subs = append(subs, info)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].Started.Before(subs[j].Started)
+	})
+	return ConnectionInfo{
+		ID:            w.id,
+		Protocol:      w.transport,
+		RemoteAddr:    w.remoteAddr,
+		Started:       w.started,
+		Subscriptions: subs,
+	}
+}
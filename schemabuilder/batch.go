@@ -0,0 +1,180 @@
+package schemabuilder
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// buildBatchFunction takes the reflect type of an object and a batch method attached to that object to build a
+// GraphQL Field which can resolve the values for many objects with a single call. The field falls back to calling
+// the method with a single object when it is not resolved as part of a list.
+func (sb *schemaBuilder) buildBatchFunction(typ reflect.Type, m *method) (*graphql.Field, error) {
+	funcCtx := &funcContext{typ: typ}
+
+	if typ.Kind() == reflect.Ptr {
+		return nil, fmt.Errorf("source-type of buildBatchFunction cannot be a pointer (got: %v)", typ)
+	}
+
+	callableFunc, err := funcCtx.getFuncVal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	in := funcCtx.getFuncInputTypes()
+	in = funcCtx.consumeContextAndBatchSource(in)
+	if !funcCtx.hasSource {
+		return nil, fmt.Errorf("%s should take a slice of %s as source", funcCtx.funcType, typ)
+	}
+
+	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, in)
+	if err != nil {
+		return nil, err
+	}
+	funcCtx.hasArgs = argParser != nil
+
+	in = funcCtx.consumeSelectionSet(in)
+
+	// We have succeeded if no arguments remain.
+	if len(in) != 0 {
+		return nil, fmt.Errorf("%s arguments should be [context], []*%s[, args][, selectionSet]", funcCtx.funcType, typ)
+	}
+
+	if err := funcCtx.parseReturnSignature(m); err != nil {
+		return nil, err
+	}
+	if !funcCtx.hasRet || funcCtx.funcType.Out(0).Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%s return values should be []result[, error]", funcCtx.funcType)
+	}
+
+	retType, err := sb.getType(funcCtx.funcType.Out(0).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := funcCtx.argsTypeMap(argType)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCostMultipliers(m, args); err != nil {
+		return nil, err
+	}
+	if m.Filter != nil {
+		return nil, fmt.Errorf("filter cannot be set on batch fields")
+	}
+
+	batchResolver := func(ctx context.Context, sources []interface{}, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) ([]interface{}, error) {
+		funcInputArgs := funcCtx.prepareBatchResolveArgs(sources, funcRawArgs, ctx, selectionSet)
+		funcOutputArgs := callableFunc.Call(funcInputArgs)
+
+		return funcCtx.extractBatchResultAndErr(funcOutputArgs, len(sources), retType)
+	}
+
+	return &graphql.Field{
+		Resolve: func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			results, err := batchResolver(ctx, []interface{}{source}, funcRawArgs, selectionSet)
+			if err != nil {
+				return nil, err
+			}
+			return results[0], nil
+		},
+		BatchResolver:     batchResolver,
+		Args:              args,
+		Type:              retType,
+		ParseArguments:    argParser.Parse,
+		Expensive:         funcCtx.hasContext,
+		External:          true,
+		Cost:              m.Cost,
+		CostMultipliers:   m.CostMultipliers,
+		Description:       m.Description,
+		ArgDescriptions:   argDescriptions(argType),
+		DeprecationReason: m.DeprecationReason,
+	}, nil
+}
+
+// consumeContextAndBatchSource works like consumeContextAndSource, but expects the source to be a slice of the
+// object type (or of pointers to it).
+func (funcCtx *funcContext) consumeContextAndBatchSource(in []reflect.Type) []reflect.Type {
+	ptr := reflect.PtrTo(funcCtx.typ)
+
+	if len(in) > 0 && in[0] == contextType {
+		funcCtx.hasContext = true
+		in = in[1:]
+	}
+
+	if len(in) > 0 && (in[0] == reflect.SliceOf(funcCtx.typ) || in[0] == reflect.SliceOf(ptr)) {
+		funcCtx.hasSource = true
+		funcCtx.isPtrFunc = in[0].Elem() == ptr
+		in = in[1:]
+	}
+
+	return in
+}
+
+// prepareBatchResolveArgs converts the provided sources, args and context into the list of reflect.Value types that
+// the batch function needs to be called.
+func (funcCtx *funcContext) prepareBatchResolveArgs(sources []interface{}, args interface{}, ctx context.Context, selectionSet *graphql.SelectionSet) []reflect.Value {
+	in := make([]reflect.Value, 0, funcCtx.funcType.NumIn())
+	if funcCtx.hasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+
+	elemTyp := funcCtx.typ
+	if funcCtx.isPtrFunc {
+		elemTyp = reflect.PtrTo(funcCtx.typ)
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(elemTyp), len(sources), len(sources))
+	for i, source := range sources {
+		sourceValue := reflect.ValueOf(source)
+		ptrSource := sourceValue.Kind() == reflect.Ptr
+		switch {
+		case ptrSource && !funcCtx.isPtrFunc:
+			slice.Index(i).Set(sourceValue.Elem())
+		case !ptrSource && funcCtx.isPtrFunc:
+			copyPtr := reflect.New(funcCtx.typ)
+			copyPtr.Elem().Set(sourceValue)
+			slice.Index(i).Set(copyPtr)
+		default:
+			slice.Index(i).Set(sourceValue)
+		}
+	}
+	in = append(in, slice)
+
+	if funcCtx.hasArgs {
+		in = append(in, reflect.ValueOf(args))
+	}
+	if funcCtx.hasSelectionSet {
+		in = append(in, reflect.ValueOf(selectionSet))
+	}
+
+	return in
+}
+
+// extractBatchResultAndErr converts the response from calling the batch function into a result for every source.
+func (funcCtx *funcContext) extractBatchResultAndErr(out []reflect.Value, count int, retType graphql.Type) ([]interface{}, error) {
+	if funcCtx.hasError {
+		if err := out[1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+	}
+
+	results := out[0]
+	if results.Len() != count {
+		return nil, fmt.Errorf("%s returned %d results for %d sources", funcCtx.funcType, results.Len(), count)
+	}
+
+	_, nonNull := retType.(*graphql.NonNull)
+	values := make([]interface{}, count)
+	for i := range values {
+		result := results.Index(i)
+		if nonNull && result.Kind() == reflect.Ptr && result.IsNil() {
+			return nil, fmt.Errorf("%s is marked non-nullable but returned a null value", funcCtx.funcType)
+		}
+		values[i] = result.Interface()
+	}
+
+	return values, nil
+}
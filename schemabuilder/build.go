@@ -32,7 +32,7 @@ type cachedType struct {
 func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 	// Support scalars and optional scalars. Scalars have precedence over structs to have eg. time.Time function as a scalar.
 	if typeName, values, ok := sb.getEnum(nodeType); ok {
-		return &graphql.NonNull{Type: &graphql.Enum{Type: typeName, Values: values, ReverseMap: sb.enumMappings[nodeType].ReverseMap}}, nil
+		return &graphql.NonNull{Type: sb.buildEnum(nodeType, typeName, values)}, nil
 	}
 
 	if typeName, ok := getScalar(nodeType); ok {
@@ -89,6 +89,18 @@ func (sb *schemaBuilder) getEnum(typ reflect.Type) (string, []string, bool) {
 	return "", nil, false
 }
 
+// buildEnum builds the graphql Enum of a registered enum type.
+func (sb *schemaBuilder) buildEnum(typ reflect.Type, name string, values []string) *graphql.Enum {
+	mapping := sb.enumMappings[typ]
+	return &graphql.Enum{
+		Type:                    name,
+		Values:                  values,
+		ReverseMap:              mapping.ReverseMap,
+		ValueDescriptions:       mapping.Descriptions,
+		ValueDeprecationReasons: mapping.DeprecationReasons,
+	}
+}
+
 // getScalar grabs the appropriate scalar graphql field type name for the passed
 // in variable reflect type.
 func getScalar(typ reflect.Type) (string, bool) {
@@ -120,4 +132,5 @@ var scalars = map[reflect.Type]string{
 	reflect.TypeOf(Timestamp(timestamp.Timestamp{})): "Timestamp",
 	reflect.TypeOf(Duration(duration.Duration{})):    "Duration",
 	reflect.TypeOf(Bytes{Value: []byte{}}):           "Bytes",
+	reflect.TypeOf(Upload{}):                         "Upload",
 }
@@ -30,12 +30,21 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 	in := funcCtx.getFuncInputTypes()
 	in = funcCtx.consumeContextAndSource(in)
 
+	var argsTyp reflect.Type
+	if len(in) > 0 && in[0] != selectionSetType {
+		argsTyp = in[0]
+	}
 	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, in)
 	if err != nil {
 		return nil, nil, err
 	}
 	funcCtx.hasArgs = argParser != nil
 
+	filter, err := buildFilter(typ, m, argsTyp)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	in = funcCtx.consumeSelectionSet(in)
 
 	// We have succeeded if no arguments remain.
@@ -58,6 +67,9 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := checkCostMultipliers(m, args); err != nil {
+		return nil, nil, err
+	}
 
 	return &graphql.Field{
 		Resolve: func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
@@ -70,12 +82,18 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 			return funcCtx.extractResultAndErr(funcOutputArgs, retType)
 
 		},
-		Args:           args,
-		Type:           retType,
-		ParseArguments: argParser.Parse,
-		Expensive:      funcCtx.hasContext,
-		External:       true,
-		LazyExecution:  funcCtx.returnsFunc,
+		Args:              args,
+		Type:              retType,
+		ParseArguments:    argParser.Parse,
+		Expensive:         funcCtx.hasContext,
+		External:          true,
+		Cost:              m.Cost,
+		CostMultipliers:   m.CostMultipliers,
+		Description:       m.Description,
+		ArgDescriptions:   argDescriptions(argType),
+		DeprecationReason: m.DeprecationReason,
+		Filter:            filter,
+		LazyExecution:     funcCtx.returnsFunc,
 		LazyResolver: func(ctx context.Context, fun interface{}) (interface{}, error) {
 			callableFunc := reflect.ValueOf(fun)
 
@@ -258,6 +276,25 @@ func (funcCtx *funcContext) argsTypeMap(argType graphql.Type) (map[string]graphq
 	return args, nil
 }
 
+// argDescriptions returns the descriptions of the arguments of a function, which are set with the description
+// tag on the fields of its args struct.
+func argDescriptions(argType graphql.Type) map[string]string {
+	if inputObject, ok := argType.(*graphql.InputObject); ok {
+		return inputObject.FieldDescriptions
+	}
+	return nil
+}
+
+// checkCostMultipliers checks that the cost multipliers of m are arguments of the function.
+func checkCostMultipliers(m *method, args map[string]graphql.Type) error {
+	for _, name := range m.CostMultipliers {
+		if _, ok := args[name]; !ok {
+			return fmt.Errorf("cost multiplier %s is not an argument", name)
+		}
+	}
+	return nil
+}
+
 // prepareResolveArgs converts the provided source, args and context into the required list of reflect.Value types that the function needs to be called.
 func (funcCtx *funcContext) prepareResolveArgs(source interface{}, hasArgs bool, args interface{}, ctx context.Context, selectionSet *graphql.SelectionSet) []reflect.Value {
 	in := make([]reflect.Value, 0, funcCtx.funcType.NumIn())
@@ -317,3 +354,40 @@ func (funcCtx *funcContext) extractResultAndErr(out []reflect.Value, retType gra
 
 	return result, nil
 }
+
+// buildFilter returns the filter of a subscription field, after checking that its function takes the arguments
+// of the field, of type argsTyp.
+func buildFilter(typ reflect.Type, m *method, argsTyp reflect.Type) (func(context.Context, interface{}, []byte) bool, error) {
+	if m.Filter == nil {
+		return nil, nil
+	}
+	if typ != reflect.TypeOf(Subscription{}) {
+		return nil, fmt.Errorf("filter can only be set on the fields of subscriptions")
+	}
+
+	fun := reflect.ValueOf(m.Filter)
+	want := []reflect.Type{reflect.TypeOf((*context.Context)(nil)).Elem()}
+	if argsTyp != nil {
+		want = append(want, argsTyp)
+	}
+	want = append(want, reflect.TypeOf([]byte(nil)))
+
+	funcTyp := fun.Type()
+	valid := fun.Kind() == reflect.Func && funcTyp.NumIn() == len(want) && funcTyp.NumOut() == 1 &&
+		funcTyp.Out(0).Kind() == reflect.Bool
+	for i := 0; valid && i < len(want); i++ {
+		valid = funcTyp.In(i) == want[i]
+	}
+	if !valid {
+		return nil, fmt.Errorf("filter %s should be func(context.Context, [args, ]payload []byte) bool", funcTyp)
+	}
+
+	return func(ctx context.Context, args interface{}, payload []byte) bool {
+		in := []reflect.Value{reflect.ValueOf(ctx)}
+		if argsTyp != nil {
+			in = append(in, reflect.ValueOf(args))
+		}
+		in = append(in, reflect.ValueOf(payload))
+		return fun.Call(in)[0].Bool()
+	}, nil
+}
@@ -88,7 +88,7 @@ func (sb *schemaBuilder) getEnumArgParser(typ reflect.Type) (*argParser, graphql
 		}
 		dest.Set(reflect.ValueOf(val).Convert(dest.Type()))
 		return nil
-	}, Type: typ}, &graphql.Enum{Type: typ.Name(), Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap}
+	}, Type: typ}, sb.buildEnum(typ, typ.Name(), values)
 
 }
 
@@ -380,4 +380,15 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 			return nil
 		},
 	},
+	reflect.TypeOf(Upload{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			v, ok := value.(*Upload)
+			if !ok {
+				return errors.New("invalid type expected upload")
+			}
+
+			dest.Set(reflect.ValueOf(*v))
+			return nil
+		},
+	},
 }
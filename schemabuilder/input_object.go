@@ -52,8 +52,9 @@ func (sb *schemaBuilder) makeInputObjectParser(typ reflect.Type) (*argParser, gr
 func (sb *schemaBuilder) generateArgParser(typ reflect.Type) (*graphql.InputObject, map[string]argField, error) {
 	fields := make(map[string]argField)
 	argType := &graphql.InputObject{
-		Name:        typ.Name(),
-		InputFields: make(map[string]graphql.Type),
+		Name:              typ.Name(),
+		InputFields:       make(map[string]graphql.Type),
+		FieldDescriptions: make(map[string]string),
 	}
 
 	// Cache type information ahead of time to catch self-reference
@@ -87,6 +88,9 @@ func (sb *schemaBuilder) generateArgParser(typ reflect.Type) (*graphql.InputObje
 			parser: parser,
 		}
 		argType.InputFields[fieldInfo.Name] = fieldArgTyp
+		if fieldInfo.Description != "" {
+			argType.FieldDescriptions[fieldInfo.Name] = fieldInfo.Description
+		}
 	}
 
 	return argType, fields, nil
@@ -96,14 +96,14 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	for _, name := range names {
 		method := methods[name]
 
-		// if method.Batch {
-		// 	batchField, err := sb.buildBatchFunctionWithFallback(typ, method)
-		// 	if err != nil {
-		// 		return err
-		// 	}
-		// 	object.Fields[name] = batchField
-		// 	continue
-		// }
+		if method.Batch {
+			batchField, err := sb.buildBatchFunction(typ, method)
+			if err != nil {
+				return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
+			}
+			object.Fields[name] = batchField
+			continue
+		}
 
 		built, err := sb.buildFunction(typ, method)
 		if err != nil {
@@ -204,6 +204,7 @@ func (sb *schemaBuilder) buildField(field reflect.StructField) (*graphql.Field,
 		},
 		Type:           retType,
 		ParseArguments: nilParseArguments,
+		Cost:           1,
 	}, nil
 }
 
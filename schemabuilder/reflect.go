@@ -24,6 +24,9 @@ type graphQLFieldInfo struct {
 	// OptionalInputField indicates that this field should be treated as an optional
 	// field on graphQL input args.
 	OptionalInputField bool
+
+	// Description is the description of the field, set with the description tag.
+	Description string
 }
 
 // parseGraphQLFieldInfo parses a struct field and returns a struct with the parsed information about the field (tag info, name, etc).
@@ -46,7 +49,7 @@ func parseGraphQLFieldInfo(field reflect.StructField) (*graphQLFieldInfo, error)
 	var key bool
 	var optional bool
 
-	return &graphQLFieldInfo{Name: name, KeyField: key, OptionalInputField: optional}, nil
+	return &graphQLFieldInfo{Name: name, KeyField: key, OptionalInputField: optional, Description: field.Tag.Get("description")}, nil
 }
 
 // makeGraphql converts a field name "MyField" into a graphQL field name "myField".
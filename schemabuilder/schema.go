@@ -44,14 +44,25 @@ func NewSchema() *Schema {
 //     "two":   enumType(2),
 //     "three": enumType(3),
 //   })
-func (s *Schema) Enum(val interface{}, enumMap interface{}) {
+//
+// The values can be documented with options such as EnumValueDescription.
+func (s *Schema) Enum(val interface{}, enumMap interface{}, options ...EnumOption) {
 	typ := reflect.TypeOf(val)
 	if s.enumTypes == nil {
 		s.enumTypes = make(map[reflect.Type]*EnumMapping)
 	}
 
 	eMap, rMap := getEnumMap(enumMap, typ)
-	s.enumTypes[typ] = &EnumMapping{Map: eMap, ReverseMap: rMap}
+	mapping := &EnumMapping{
+		Map:                eMap,
+		ReverseMap:         rMap,
+		Descriptions:       make(map[string]string),
+		DeprecationReasons: make(map[string]string),
+	}
+	for _, option := range options {
+		option(mapping)
+	}
+	s.enumTypes[typ] = mapping
 }
 
 func getEnumMap(enumMap interface{}, typ reflect.Type) (map[string]interface{}, map[interface{}]string) {
@@ -260,6 +271,12 @@ func copyObject(object *Object) *Object {
 		copy.Methods[name] = &method{
 			MarkedNonNullable: m.MarkedNonNullable,
 			Fn:                m.Fn,
+			Batch:             m.Batch,
+			Cost:              m.Cost,
+			CostMultipliers:   m.CostMultipliers,
+			Description:       m.Description,
+			DeprecationReason: m.DeprecationReason,
+			Filter:            m.Filter,
 		}
 	}
 
@@ -282,8 +299,10 @@ func copyInputObject(input *InputObject) *InputObject {
 
 func copyEnumMappings(mapping *EnumMapping) *EnumMapping {
 	enum := &EnumMapping{
-		Map:        make(map[string]interface{}, len(mapping.Map)),
-		ReverseMap: make(map[interface{}]string, len(mapping.ReverseMap)),
+		Map:                make(map[string]interface{}, len(mapping.Map)),
+		ReverseMap:         make(map[interface{}]string, len(mapping.ReverseMap)),
+		Descriptions:       make(map[string]string, len(mapping.Descriptions)),
+		DeprecationReasons: make(map[string]string, len(mapping.DeprecationReasons)),
 	}
 
 	for key, value := range mapping.Map {
@@ -294,5 +313,13 @@ func copyEnumMappings(mapping *EnumMapping) *EnumMapping {
 		enum.ReverseMap[key] = value
 	}
 
+	for key, value := range mapping.Descriptions {
+		enum.Descriptions[key] = value
+	}
+
+	for key, value := range mapping.DeprecationReasons {
+		enum.DeprecationReasons[key] = value
+	}
+
 	return enum
 }
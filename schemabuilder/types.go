@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"go.appointy.com/jaal/graphql"
 )
 
 //Object - an Object represents a Go type and set of methods to be converted into an Object in a GraphQL schema.
@@ -47,12 +49,103 @@ type Methods map[string]*method
 type method struct {
 	MarkedNonNullable bool
 	Fn                interface{}
+
+	// Batch is set for the methods registered using BatchFieldFunc.
+	Batch bool
+
+	Cost            int
+	CostMultipliers []string
+
+	Description       string
+	DeprecationReason string
+
+	// Filter is set for the subscription fields registered with the Filter option.
+	Filter interface{}
+}
+
+// A FieldFuncOption configures a field registered with FieldFunc or BatchFieldFunc.
+type FieldFuncOption func(*method)
+
+// Cost sets the cost of resolving the field, which adds up to the complexity of the queries selecting it.
+// Fields cost 1 by default.
+func Cost(cost int) FieldFuncOption {
+	return func(m *method) {
+		m.Cost = cost
+	}
+}
+
+// Description documents the field in the introspection of the schema.
+func Description(description string) FieldFuncOption {
+	return func(m *method) {
+		m.Description = description
+	}
+}
+
+// Deprecated marks the field as deprecated in the introspection of the schema, with the reason it should no
+// longer be used, which usually names its replacement.
+func Deprecated(reason string) FieldFuncOption {
+	if reason == "" {
+		reason = graphql.DefaultDeprecationReason
+	}
+	return func(m *method) {
+		m.DeprecationReason = reason
+	}
+}
+
+// CostMultipliers names the arguments which bound the length of the list returned by the field, such as
+// "first". The complexity of the selections of the field is multiplied by the values of those arguments.
+func CostMultipliers(args ...string) FieldFuncOption {
+	return func(m *method) {
+		m.CostMultipliers = args
+	}
+}
+
+// Filter sets the function deciding if an event is relevant to a subscription, which is called with the
+// arguments of the subscription and the payload of the event when it is routed, before it is queued. The events
+// it rejects are neither queued nor executed, so f must be fast and must not block. The function f must be of
+// the form:
+// func(ctx context.Context, args struct {}, payload []byte) bool
+// where args has the type of the arguments of the field, and is omitted when the field has none.
+//
+// For example, a subscription to the posts with a tag only receives the events of those posts:
+//    subscription.FieldFunc("postStream", func(source *schemabuilder.Subscription, args struct{ Tag string }) *Post {
+//        return decodePost(source.Payload)
+//    }, schemabuilder.Filter(func(ctx context.Context, args struct{ Tag string }, payload []byte) bool {
+//        return decodePost(payload).Tag == args.Tag
+//    }))
+func Filter(f interface{}) FieldFuncOption {
+	return func(m *method) {
+		m.Filter = f
+	}
 }
 
 // EnumMapping is a representation of an enum that includes both the mapping and reverse mapping.
 type EnumMapping struct {
 	Map        map[string]interface{}
 	ReverseMap map[interface{}]string
+
+	Descriptions       map[string]string
+	DeprecationReasons map[string]string
+}
+
+// An EnumOption documents the values of an enum registered with Schema.Enum.
+type EnumOption func(*EnumMapping)
+
+// EnumValueDescription documents the enum value named value in the introspection of the schema.
+func EnumValueDescription(value, description string) EnumOption {
+	return func(m *EnumMapping) {
+		m.Descriptions[value] = description
+	}
+}
+
+// EnumValueDeprecated marks the enum value named value as deprecated in the introspection of the schema.
+func EnumValueDeprecated(value, reason string) EnumOption {
+	if reason == "" {
+		reason = graphql.DefaultDeprecationReason
+	}
+	return func(m *EnumMapping) {
+		m.DeprecationReasons[value] = reason
+	}
 }
 
 // InterfaceObj is a representation of graphql interface
@@ -100,12 +193,49 @@ var unionType = reflect.TypeOf(Union{})
 //        userID, err := db.AddUser(ctx, args.FirstName, args.LastName)
 //        return userID, err
 //    })
-func (s *Object) FieldFunc(name string, f interface{}) {
+//
+// Options such as Cost configure the field further.
+func (s *Object) FieldFunc(name string, f interface{}, options ...FieldFuncOption) {
 	if s.Methods == nil {
 		s.Methods = make(Methods)
 	}
 
-	m := &method{Fn: f}
+	m := &method{Fn: f, Cost: 1}
+	for _, option := range options {
+		option(m)
+	}
+
+	if _, ok := s.Methods[name]; ok {
+		panic("duplicate method")
+	}
+	s.Methods[name] = m
+}
+
+// BatchFieldFunc exposes a field on an object which is resolved for many objects at once. When
+// the field is queried on a list of objects, the function is called a single time with every
+// object of the list instead of once per object. The function f can take a number of optional
+// arguments:
+// func([ctx context.Context], o []*Type, [args struct {}]) ([]Result, [error])
+//
+// The function must return exactly one result for every object it receives, in the same order.
+//
+// For example, the department of every employee of a list can be fetched with a single call:
+//    employee.BatchFieldFunc("department", func(ctx context.Context, in []*Employee) ([]*Department, error) {
+//        ids := make([]string, len(in))
+//        for i, e := range in {
+//            ids[i] = e.DepartmentId
+//        }
+//        return db.GetDepartments(ctx, ids)
+//    })
+func (s *Object) BatchFieldFunc(name string, f interface{}, options ...FieldFuncOption) {
+	if s.Methods == nil {
+		s.Methods = make(Methods)
+	}
+
+	m := &method{Fn: f, Batch: true, Cost: 1}
+	for _, option := range options {
+		option(m)
+	}
 
 	if _, ok := s.Methods[name]; ok {
 		panic("duplicate method")
@@ -290,3 +420,13 @@ func (b Bytes) MarshalJSON() ([]byte, error) {
 	}
 	return data, nil
 }
+
+// Upload is a file uploaded with a multipart request, following the GraphQL multipart request specification.
+// The content of the file is read from the embedded io.Reader, which is only valid while the request is
+// executed.
+type Upload struct {
+	io.Reader
+	Filename    string
+	ContentType string
+	Size        int64
+}
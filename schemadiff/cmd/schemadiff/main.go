@@ -0,0 +1,69 @@
+// Command schemadiff prints the changes between two versions of a GraphQL schema, and exits with status 1 if
+// any of them is breaking.
+//
+// Usage:
+//
+//	schemadiff [-all] old new
+//
+// Each schema is read from a file holding either SDL or the introspection JSON returned by
+// introspection.ComputeSchemaJSON.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"go.appointy.com/jaal/schemadiff"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("schemadiff: ")
+
+	all := flag.Bool("all", false, "also print the safe changes")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: schemadiff [-all] old new")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := load(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := load(flag.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changes := schemadiff.Compare(old, new)
+	for _, change := range changes {
+		if *all || change.Level != schemadiff.Safe {
+			fmt.Println(change)
+		}
+	}
+
+	if schemadiff.HasBreaking(changes) {
+		os.Exit(1)
+	}
+}
+
+func load(path string) (*schemadiff.Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := schemadiff.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return schema, nil
+}
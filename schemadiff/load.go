@@ -0,0 +1,423 @@
+package schemadiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/graphql-go/graphql/language/source"
+	"go.appointy.com/jaal/graphql"
+)
+
+// builtinScalars are the scalars defined by the spec, which every schema has.
+var builtinScalars = map[string]bool{
+	"Int":     true,
+	"Float":   true,
+	"String":  true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// Load loads a schema from introspection JSON if data is a JSON object, or from SDL otherwise.
+func Load(data []byte) (*Schema, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return FromIntrospection(data)
+	}
+	return FromSDL(string(data))
+}
+
+// FromSchema converts a built schema.
+func FromSchema(schema *graphql.Schema) *Schema {
+	s := newSchema()
+	roots := map[string]graphql.Type{"query": schema.Query, "mutation": schema.Mutation, "subscription": schema.Subscription}
+	for operation, root := range roots {
+		if root == nil {
+			continue
+		}
+		typ := namedType(root)
+		s.Roots[operation] = typ.String()
+		s.collect(typ)
+	}
+	s.normalize()
+	return s
+}
+
+func (s *Schema) collect(typ graphql.Type) {
+	typ = namedType(typ)
+	name := typ.String()
+	if builtinScalars[name] || strings.HasPrefix(name, "__") || s.Types[name] != nil {
+		return
+	}
+
+	t := &Type{Name: name}
+	s.Types[name] = t
+
+	switch typ := typ.(type) {
+	case *graphql.Scalar:
+		t.Kind = "SCALAR"
+
+	case *graphql.Enum:
+		t.Kind = "ENUM"
+		t.EnumValues = make(map[string]bool)
+		for _, value := range typ.Values {
+			t.EnumValues[value] = typ.ValueDeprecationReasons[value] != ""
+		}
+
+	case *graphql.Object:
+		t.Kind = "OBJECT"
+		t.Fields = s.collectFields(typ.Fields)
+		t.Interfaces = make(map[string]bool)
+		for name, iface := range typ.Interfaces {
+			t.Interfaces[name] = true
+			s.collect(iface)
+		}
+
+	case *graphql.Interface:
+		t.Kind = "INTERFACE"
+		t.Fields = s.collectFields(typ.Fields)
+		for _, obj := range typ.Types {
+			s.collect(obj)
+		}
+
+	case *graphql.Union:
+		t.Kind = "UNION"
+		t.PossibleTypes = make(map[string]bool)
+		for name, obj := range typ.Types {
+			t.PossibleTypes[name] = true
+			s.collect(obj)
+		}
+
+	case *graphql.InputObject:
+		t.Kind = "INPUT_OBJECT"
+		t.InputFields = make(map[string]*InputValue)
+		for name, fieldType := range typ.InputFields {
+			t.InputFields[name] = &InputValue{Type: typeRef(fieldType)}
+			s.collect(fieldType)
+		}
+	}
+}
+
+func (s *Schema) collectFields(fields map[string]*graphql.Field) map[string]*Field {
+	result := make(map[string]*Field)
+	for name, field := range fields {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		f := &Field{Type: typeRef(field.Type), Args: make(map[string]*InputValue), Deprecated: field.DeprecationReason != ""}
+		s.collect(field.Type)
+		for name, argType := range field.Args {
+			f.Args[name] = &InputValue{Type: typeRef(argType)}
+			s.collect(argType)
+		}
+		result[name] = f
+	}
+	return result
+}
+
+func namedType(typ graphql.Type) graphql.Type {
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		return namedType(typ.Type)
+	case *graphql.List:
+		return namedType(typ.Type)
+	default:
+		return typ
+	}
+}
+
+func typeRef(typ graphql.Type) *graphql.TypeRef {
+	switch typ := typ.(type) {
+	case *graphql.NonNull:
+		ref := typeRef(typ.Type)
+		ref.NonNull = true
+		return ref
+	case *graphql.List:
+		return &graphql.TypeRef{Elem: typeRef(typ.Type)}
+	default:
+		return &graphql.TypeRef{Name: typ.String()}
+	}
+}
+
+// introspectionType mirrors the types returned by the introspection query.
+type introspectionType struct {
+	Kind          string               `json:"kind"`
+	Name          string               `json:"name"`
+	OfType        *introspectionType   `json:"ofType"`
+	Fields        []introspectionField `json:"fields"`
+	InputFields   []introspectionValue `json:"inputFields"`
+	Interfaces    []introspectionType  `json:"interfaces"`
+	PossibleTypes []introspectionType  `json:"possibleTypes"`
+	EnumValues    []struct {
+		Name         string `json:"name"`
+		IsDeprecated bool   `json:"isDeprecated"`
+	} `json:"enumValues"`
+}
+
+type introspectionField struct {
+	Name         string               `json:"name"`
+	Args         []introspectionValue `json:"args"`
+	Type         *introspectionType   `json:"type"`
+	IsDeprecated bool                 `json:"isDeprecated"`
+}
+
+type introspectionValue struct {
+	Name         string             `json:"name"`
+	Type         *introspectionType `json:"type"`
+	DefaultValue *string            `json:"defaultValue"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionType  `json:"queryType"`
+	MutationType     *introspectionType  `json:"mutationType"`
+	SubscriptionType *introspectionType  `json:"subscriptionType"`
+	Types            []introspectionType `json:"types"`
+}
+
+// FromIntrospection converts the result of the introspection query, as returned by
+// introspection.ComputeSchemaJSON. The result may also be wrapped in the "data" key of a response.
+func FromIntrospection(data []byte) (*Schema, error) {
+	var result struct {
+		Schema *introspectionSchema `json:"__schema"`
+		Data   *struct {
+			Schema *introspectionSchema `json:"__schema"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	schema := result.Schema
+	if schema == nil && result.Data != nil {
+		schema = result.Data.Schema
+	}
+	if schema == nil {
+		return nil, errors.New("introspection result has no __schema")
+	}
+
+	s := newSchema()
+	roots := map[string]*introspectionType{"query": schema.QueryType, "mutation": schema.MutationType, "subscription": schema.SubscriptionType}
+	for operation, root := range roots {
+		if root != nil {
+			s.Roots[operation] = root.Name
+		}
+	}
+
+	for _, typ := range schema.Types {
+		if builtinScalars[typ.Name] || strings.HasPrefix(typ.Name, "__") {
+			continue
+		}
+
+		t := &Type{Name: typ.Name, Kind: typ.Kind}
+		switch typ.Kind {
+		case "OBJECT", "INTERFACE":
+			t.Fields = make(map[string]*Field)
+			for _, field := range typ.Fields {
+				if strings.HasPrefix(field.Name, "__") {
+					continue
+				}
+
+				f := &Field{Type: introspectionTypeRef(field.Type), Args: make(map[string]*InputValue), Deprecated: field.IsDeprecated}
+				for _, arg := range field.Args {
+					f.Args[arg.Name] = introspectionInputValue(arg)
+				}
+				t.Fields[field.Name] = f
+			}
+			if typ.Kind == "OBJECT" {
+				t.Interfaces = introspectionNames(typ.Interfaces)
+			}
+
+		case "UNION":
+			t.PossibleTypes = introspectionNames(typ.PossibleTypes)
+
+		case "ENUM":
+			t.EnumValues = make(map[string]bool)
+			for _, value := range typ.EnumValues {
+				t.EnumValues[value.Name] = value.IsDeprecated
+			}
+
+		case "INPUT_OBJECT":
+			t.InputFields = make(map[string]*InputValue)
+			for _, field := range typ.InputFields {
+				t.InputFields[field.Name] = introspectionInputValue(field)
+			}
+		}
+		s.Types[t.Name] = t
+	}
+
+	s.normalize()
+	return s, nil
+}
+
+func introspectionTypeRef(typ *introspectionType) *graphql.TypeRef {
+	if typ == nil {
+		return &graphql.TypeRef{}
+	}
+
+	switch typ.Kind {
+	case "NON_NULL":
+		ref := introspectionTypeRef(typ.OfType)
+		ref.NonNull = true
+		return ref
+	case "LIST":
+		return &graphql.TypeRef{Elem: introspectionTypeRef(typ.OfType)}
+	default:
+		return &graphql.TypeRef{Name: typ.Name}
+	}
+}
+
+func introspectionInputValue(value introspectionValue) *InputValue {
+	v := &InputValue{Type: introspectionTypeRef(value.Type)}
+	if value.DefaultValue != nil {
+		v.DefaultValue = *value.DefaultValue
+	}
+	return v
+}
+
+func introspectionNames(types []introspectionType) map[string]bool {
+	names := make(map[string]bool)
+	for _, typ := range types {
+		names[typ.Name] = true
+	}
+	return names
+}
+
+// FromSDL parses a schema written in the GraphQL schema definition language, such as the output of
+// graphql.PrintSchema. Without a schema definition, the root types are the types named Query, Mutation and
+// Subscription.
+func FromSDL(sdl string) (*Schema, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(sdl), Name: "GraphQL SDL"})})
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSchema()
+	var schemaDefinition *ast.SchemaDefinition
+	for _, definition := range document.Definitions {
+		var t *Type
+		switch definition := definition.(type) {
+		case *ast.SchemaDefinition:
+			schemaDefinition = definition
+			continue
+
+		case *ast.ScalarDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "SCALAR"}
+
+		case *ast.ObjectDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "OBJECT", Fields: sdlFields(definition.Fields), Interfaces: make(map[string]bool)}
+			for _, iface := range definition.Interfaces {
+				t.Interfaces[iface.Name.Value] = true
+			}
+
+		case *ast.InterfaceDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "INTERFACE", Fields: sdlFields(definition.Fields)}
+
+		case *ast.UnionDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "UNION", PossibleTypes: make(map[string]bool)}
+			for _, member := range definition.Types {
+				t.PossibleTypes[member.Name.Value] = true
+			}
+
+		case *ast.EnumDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "ENUM", EnumValues: make(map[string]bool)}
+			for _, value := range definition.Values {
+				t.EnumValues[value.Name.Value] = isDeprecated(value.Directives)
+			}
+
+		case *ast.InputObjectDefinition:
+			t = &Type{Name: definition.Name.Value, Kind: "INPUT_OBJECT", InputFields: sdlInputValues(definition.Fields)}
+
+		default:
+			return nil, fmt.Errorf("unsupported definition %s", definition.GetKind())
+		}
+
+		if s.Types[t.Name] != nil {
+			return nil, fmt.Errorf("type %s is defined twice", t.Name)
+		}
+		if !builtinScalars[t.Name] {
+			s.Types[t.Name] = t
+		}
+	}
+
+	if schemaDefinition != nil {
+		for _, operationType := range schemaDefinition.OperationTypes {
+			s.Roots[operationType.Operation] = operationType.Type.Name.Value
+		}
+	} else {
+		for operation, name := range map[string]string{"query": "Query", "mutation": "Mutation", "subscription": "Subscription"} {
+			if s.Types[name] != nil {
+				s.Roots[operation] = name
+			}
+		}
+	}
+
+	s.normalize()
+	return s, nil
+}
+
+func sdlFields(definitions []*ast.FieldDefinition) map[string]*Field {
+	fields := make(map[string]*Field)
+	for _, definition := range definitions {
+		fields[definition.Name.Value] = &Field{
+			Type:       sdlTypeRef(definition.Type),
+			Args:       sdlInputValues(definition.Arguments),
+			Deprecated: isDeprecated(definition.Directives),
+		}
+	}
+	return fields
+}
+
+func sdlInputValues(definitions []*ast.InputValueDefinition) map[string]*InputValue {
+	values := make(map[string]*InputValue)
+	for _, definition := range definitions {
+		value := &InputValue{Type: sdlTypeRef(definition.Type)}
+		if definition.DefaultValue != nil {
+			value.DefaultValue = fmt.Sprint(printer.Print(definition.DefaultValue))
+		}
+		values[definition.Name.Value] = value
+	}
+	return values
+}
+
+func sdlTypeRef(typ ast.Type) *graphql.TypeRef {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		ref := sdlTypeRef(typ.Type)
+		ref.NonNull = true
+		return ref
+	case *ast.List:
+		return &graphql.TypeRef{Elem: sdlTypeRef(typ.Type)}
+	case *ast.Named:
+		return &graphql.TypeRef{Name: typ.Name.Value}
+	default:
+		return &graphql.TypeRef{}
+	}
+}
+
+func isDeprecated(directives []*ast.Directive) bool {
+	for _, directive := range directives {
+		if directive.Name.Value == "deprecated" {
+			return true
+		}
+	}
+	return false
+}
+
+func newSchema() *Schema {
+	return &Schema{Roots: make(map[string]string), Types: make(map[string]*Type)}
+}
+
+// normalize removes the root objects without fields, which schemabuilder builds for the mutations and
+// subscriptions even when there are none, and which graphql.PrintSchema omits.
+func (s *Schema) normalize() {
+	for operation, name := range s.Roots {
+		if t := s.Types[name]; t != nil && t.Kind == "OBJECT" && len(t.Fields) == 0 {
+			delete(s.Types, name)
+			delete(s.Roots, operation)
+		}
+	}
+}
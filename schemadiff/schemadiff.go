@@ -0,0 +1,325 @@
+// Package schemadiff compares two versions of a GraphQL schema and classifies their differences, so that
+// changes breaking existing clients can be caught before they are deployed.
+//
+// The schemas can be loaded from a built *graphql.Schema, from the introspection JSON returned by
+// introspection.ComputeSchemaJSON, or from SDL such as the output of graphql.PrintSchema.
+package schemadiff
+
+import (
+	"fmt"
+	"sort"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// Level is the severity of a change.
+type Level int
+
+const (
+	// Safe changes cannot break existing clients.
+	Safe Level = iota
+	// Dangerous changes do not break valid queries, but may change the behavior of existing clients, for
+	// example a new enum value a client does not know how to handle.
+	Dangerous
+	// Breaking changes make existing queries fail or return values of unexpected types.
+	Breaking
+)
+
+func (l Level) String() string {
+	switch l {
+	case Safe:
+		return "SAFE"
+	case Dangerous:
+		return "DANGEROUS"
+	case Breaking:
+		return "BREAKING"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// Change is a difference between two schemas.
+type Change struct {
+	Level Level
+	// Path is the schema coordinate of the changed element, such as "User.name" or "Query.users(first:)".
+	Path    string
+	Message string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Level, c.Path, c.Message)
+}
+
+// Schema is a schema in the form it is compared, independent of where it was loaded from.
+type Schema struct {
+	// Roots maps the operations to the names of their root types, for example "query" to "Query".
+	Roots map[string]string
+	Types map[string]*Type
+}
+
+// Type is a named type of a schema. The builtin scalars and the introspection types are not included.
+type Type struct {
+	Name string
+	// Kind is the introspection kind of the type, such as "OBJECT" or "ENUM".
+	Kind        string
+	Fields      map[string]*Field
+	InputFields map[string]*InputValue
+	// Interfaces are the interfaces implemented by an object.
+	Interfaces map[string]bool
+	// PossibleTypes are the members of a union.
+	PossibleTypes map[string]bool
+	// EnumValues maps the values of an enum to whether they are deprecated.
+	EnumValues map[string]bool
+}
+
+// Field is a field of an object or an interface.
+type Field struct {
+	Type       *graphql.TypeRef
+	Args       map[string]*InputValue
+	Deprecated bool
+}
+
+// InputValue is an argument of a field or a field of an input object.
+type InputValue struct {
+	Type *graphql.TypeRef
+	// DefaultValue is the default value in GraphQL syntax, or empty if there is none.
+	DefaultValue string
+}
+
+func (v *InputValue) required() bool {
+	return v.Type.NonNull && v.DefaultValue == ""
+}
+
+// Compare returns the changes from the old schema to the new one, sorted by path.
+func Compare(old, new *Schema) []Change {
+	d := &differ{}
+
+	for _, operation := range []string{"query", "mutation", "subscription"} {
+		oldRoot, newRoot := old.Roots[operation], new.Roots[operation]
+		switch {
+		case oldRoot == newRoot:
+		case oldRoot == "":
+			d.add(Safe, newRoot, fmt.Sprintf("%s root type was added", operation))
+		case newRoot == "":
+			d.add(Breaking, oldRoot, fmt.Sprintf("%s root type was removed", operation))
+		default:
+			d.add(Breaking, newRoot, fmt.Sprintf("%s root type changed from %s to %s", operation, oldRoot, newRoot))
+		}
+	}
+
+	for _, name := range sortedKeys(old.Types) {
+		oldType, newType := old.Types[name], new.Types[name]
+		if newType == nil {
+			d.add(Breaking, name, "type was removed")
+			continue
+		}
+		if oldType.Kind != newType.Kind {
+			d.add(Breaking, name, fmt.Sprintf("type changed from %s to %s", oldType.Kind, newType.Kind))
+			continue
+		}
+		d.compareType(oldType, newType)
+	}
+	for _, name := range sortedKeys(new.Types) {
+		if old.Types[name] == nil {
+			d.add(Safe, name, "type was added")
+		}
+	}
+
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return d.changes
+}
+
+// HasBreaking reports whether any of the changes is breaking.
+func HasBreaking(changes []Change) bool {
+	for _, change := range changes {
+		if change.Level == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(level Level, path, message string) {
+	d.changes = append(d.changes, Change{Level: level, Path: path, Message: message})
+}
+
+func (d *differ) compareType(old, new *Type) {
+	for _, name := range sortedKeys(old.Fields) {
+		path := old.Name + "." + name
+		oldField, newField := old.Fields[name], new.Fields[name]
+		if newField == nil {
+			d.add(Breaking, path, "field was removed")
+			continue
+		}
+		d.compareField(path, oldField, newField)
+	}
+	for _, name := range sortedKeys(new.Fields) {
+		if old.Fields[name] == nil {
+			d.add(Safe, new.Name+"."+name, "field was added")
+		}
+	}
+
+	for _, name := range sortedKeys(old.InputFields) {
+		path := old.Name + "." + name
+		oldField, newField := old.InputFields[name], new.InputFields[name]
+		if newField == nil {
+			d.add(Breaking, path, "input field was removed")
+			continue
+		}
+		d.compareInputValue(path, "input field", oldField, newField)
+	}
+	for _, name := range sortedKeys(new.InputFields) {
+		if old.InputFields[name] != nil {
+			continue
+		}
+		if new.InputFields[name].required() {
+			d.add(Breaking, new.Name+"."+name, "required input field was added")
+		} else {
+			d.add(Dangerous, new.Name+"."+name, "optional input field was added")
+		}
+	}
+
+	d.compareSet(old.Name, "interface", old.Interfaces, new.Interfaces)
+	d.compareSet(old.Name, "union member", old.PossibleTypes, new.PossibleTypes)
+
+	for _, value := range sortedKeys(old.EnumValues) {
+		deprecated, ok := new.EnumValues[value]
+		if !ok {
+			d.add(Breaking, old.Name+"."+value, "enum value was removed")
+		} else if deprecated && !old.EnumValues[value] {
+			d.add(Safe, old.Name+"."+value, "enum value was deprecated")
+		}
+	}
+	for _, value := range sortedKeys(new.EnumValues) {
+		if _, ok := old.EnumValues[value]; !ok {
+			d.add(Dangerous, new.Name+"."+value, "enum value was added")
+		}
+	}
+}
+
+func (d *differ) compareField(path string, old, new *Field) {
+	if old.Type.String() != new.Type.String() {
+		level := Breaking
+		if isSafeOutputChange(old.Type, new.Type) {
+			level = Safe
+		}
+		d.add(level, path, fmt.Sprintf("type changed from %s to %s", old.Type, new.Type))
+	}
+	if new.Deprecated && !old.Deprecated {
+		d.add(Safe, path, "field was deprecated")
+	}
+
+	for _, name := range sortedKeys(old.Args) {
+		argPath := fmt.Sprintf("%s(%s:)", path, name)
+		oldArg, newArg := old.Args[name], new.Args[name]
+		if newArg == nil {
+			d.add(Breaking, argPath, "argument was removed")
+			continue
+		}
+		d.compareInputValue(argPath, "argument", oldArg, newArg)
+	}
+	for _, name := range sortedKeys(new.Args) {
+		if old.Args[name] != nil {
+			continue
+		}
+		argPath := fmt.Sprintf("%s(%s:)", path, name)
+		if new.Args[name].required() {
+			d.add(Breaking, argPath, "required argument was added")
+		} else {
+			d.add(Dangerous, argPath, "optional argument was added")
+		}
+	}
+}
+
+func (d *differ) compareInputValue(path, kind string, old, new *InputValue) {
+	if old.Type.String() != new.Type.String() {
+		level := Breaking
+		if isSafeInputChange(old.Type, new.Type) {
+			level = Safe
+		}
+		d.add(level, path, fmt.Sprintf("%s type changed from %s to %s", kind, old.Type, new.Type))
+	}
+	if old.DefaultValue != new.DefaultValue {
+		switch {
+		case new.DefaultValue == "" && new.Type.NonNull:
+			d.add(Breaking, path, fmt.Sprintf("%s default value %s was removed, which makes it required", kind, old.DefaultValue))
+		case old.DefaultValue != "":
+			d.add(Dangerous, path, fmt.Sprintf("%s default value changed from %s to %s", kind, formatDefault(old.DefaultValue), formatDefault(new.DefaultValue)))
+		}
+	}
+}
+
+// compareSet compares the interfaces of an object or the members of a union.
+func (d *differ) compareSet(path, kind string, old, new map[string]bool) {
+	for _, name := range sortedKeys(old) {
+		if !new[name] {
+			d.add(Breaking, path, fmt.Sprintf("%s %s was removed", kind, name))
+		}
+	}
+	for _, name := range sortedKeys(new) {
+		if !old[name] {
+			d.add(Dangerous, path, fmt.Sprintf("%s %s was added", kind, name))
+		}
+	}
+}
+
+func formatDefault(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// isSafeOutputChange reports whether a field of type old can return a value of type new without breaking
+// clients, which is the case when new is at least as strict as old.
+func isSafeOutputChange(old, new *graphql.TypeRef) bool {
+	if old.NonNull && !new.NonNull {
+		return false
+	}
+	if old.Elem != nil {
+		return new.Elem != nil && isSafeOutputChange(old.Elem, new.Elem)
+	}
+	return new.Elem == nil && old.Name == new.Name
+}
+
+// isSafeInputChange reports whether an argument or input field of type old can accept the values of type new
+// without breaking clients, which is the case when new is at most as strict as old.
+func isSafeInputChange(old, new *graphql.TypeRef) bool {
+	if new.NonNull && !old.NonNull {
+		return false
+	}
+	if old.Elem != nil {
+		return new.Elem != nil && isSafeInputChange(old.Elem, new.Elem)
+	}
+	return new.Elem == nil && old.Name == new.Name
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*Type:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*Field:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*InputValue:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]bool:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,181 @@
+package schemadiff_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/introspection"
+	"go.appointy.com/jaal/schemabuilder"
+	"go.appointy.com/jaal/schemadiff"
+)
+
+const oldSDL = `
+type Query {
+  user(id: ID!): User
+  users(first: Int = 10): [User!]!
+  search(text: String): [Result]
+  legacy: String
+}
+
+type User {
+  id: ID!
+  name: String
+  email: String!
+  role: Role!
+}
+
+type Bot {
+  id: ID!
+}
+
+union Result = User | Bot
+
+enum Role {
+  ADMIN
+  MEMBER
+  GUEST
+}
+
+input Filter {
+  role: Role
+  since: String
+}
+
+scalar Time
+`
+
+const newSDL = `
+type Query {
+  user(id: ID!, tenant: ID!): User
+  users(first: Int = 20, filter: Filter): [User!]!
+  search(text: String!): [Result]
+  legacy: String @deprecated
+}
+
+type User {
+  id: ID!
+  name: String!
+  email: String
+  role: Role!
+  createdAt: Time
+}
+
+type Bot {
+  id: ID!
+}
+
+type Team {
+  id: ID!
+}
+
+union Result = User | Team
+
+enum Role {
+  ADMIN
+  MEMBER
+  OWNER
+}
+
+input Filter {
+  role: Role
+  active: Boolean!
+}
+`
+
+func TestCompare(t *testing.T) {
+	old, err := schemadiff.FromSDL(oldSDL)
+	require.NoError(t, err)
+	new, err := schemadiff.FromSDL(newSDL)
+	require.NoError(t, err)
+
+	changes := schemadiff.Compare(old, new)
+
+	var actual []string
+	for _, change := range changes {
+		actual = append(actual, change.String())
+	}
+	assert.Equal(t, []string{
+		"BREAKING Filter.active: required input field was added",
+		"BREAKING Filter.since: input field was removed",
+		"SAFE Query.legacy: field was deprecated",
+		"BREAKING Query.search(text:): argument type changed from String to String!",
+		"BREAKING Query.user(tenant:): required argument was added",
+		"DANGEROUS Query.users(filter:): optional argument was added",
+		"DANGEROUS Query.users(first:): argument default value changed from 10 to 20",
+		"BREAKING Result: union member Bot was removed",
+		"DANGEROUS Result: union member Team was added",
+		"BREAKING Role.GUEST: enum value was removed",
+		"DANGEROUS Role.OWNER: enum value was added",
+		"SAFE Team: type was added",
+		"BREAKING Time: type was removed",
+		"SAFE User.createdAt: field was added",
+		"BREAKING User.email: type changed from String! to String",
+		"SAFE User.name: type changed from String to String!",
+	}, actual)
+	assert.True(t, schemadiff.HasBreaking(changes))
+
+	assert.Empty(t, schemadiff.Compare(old, old))
+}
+
+func TestCompareKinds(t *testing.T) {
+	old, err := schemadiff.FromSDL(`
+schema { query: RootQuery }
+type RootQuery { node: Node, list: [[Int!]] }
+interface Node { id: ID! }
+`)
+	require.NoError(t, err)
+	new, err := schemadiff.FromSDL(`
+type Query { node: Node, list: [[Int!]!]! }
+type Node { id: ID! }
+`)
+	require.NoError(t, err)
+
+	var actual []string
+	for _, change := range schemadiff.Compare(old, new) {
+		actual = append(actual, change.String())
+	}
+	assert.Equal(t, []string{
+		"BREAKING Node: type changed from INTERFACE to OBJECT",
+		"BREAKING Query: query root type changed from RootQuery to Query",
+		"SAFE Query: type was added",
+		"BREAKING RootQuery: type was removed",
+	}, actual)
+}
+
+type User struct {
+	Id   string
+	Name string
+}
+
+type role int32
+
+func TestLoad(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Enum(role(0), map[string]role{"ADMIN": 0, "MEMBER": 1}, schemabuilder.EnumValueDeprecated("MEMBER", ""))
+	user := builder.Object("User", User{})
+	user.FieldFunc("id", func(in User) schemabuilder.ID { return schemabuilder.ID{Value: in.Id} })
+	user.FieldFunc("name", func(in User) string { return in.Name }, schemabuilder.Deprecated("Use id."))
+	builder.Query().FieldFunc("users", func(ctx context.Context, args struct {
+		First *int32
+		Role  role
+	}) []*User {
+		return nil
+	})
+
+	schema := schemadiff.FromSchema(builder.MustBuild())
+	assert.Equal(t, map[string]string{"query": "Query"}, schema.Roots)
+	assert.Len(t, schema.Types, 3)
+
+	sdl, err := schemadiff.Load([]byte(graphql.PrintSchema(builder.MustBuild())))
+	require.NoError(t, err)
+	assert.Empty(t, schemadiff.Compare(schema, sdl))
+
+	data, err := introspection.ComputeSchemaJSON(*builder)
+	require.NoError(t, err)
+	json, err := schemadiff.Load(data)
+	require.NoError(t, err)
+	assert.Empty(t, schemadiff.Compare(schema, json))
+}
@@ -0,0 +1,208 @@
+package jaal
+
+// This file contains the Server-Sent Events transport of subscriptions, which follows the distinct connections
+// mode of the GraphQL over SSE protocol: every request streams the results of a single subscription, and the
+// stream ends with a complete event.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+// defaultKeepAlive is the interval of the heartbeats of the event streams, unless set with WithKeepAlive.
+const defaultKeepAlive = 15 * time.Second
+
+// WithKeepAlive sets the interval of the heartbeats sent on the event streams of subscriptions, which keep
+// proxies from closing idle connections. It defaults to 15 seconds, and 0 disables the heartbeats.
+func WithKeepAlive(interval time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.KeepAlive = interval
+	}
+}
+
+// wantsEventStream checks if a request asks for an event stream.
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// serveSSE streams the results of the subscription of a request, sent as the parameters of a GET request or as
+// the JSON body of a POST request, until the request is cancelled or the handler is shut down.
+func (h *SubHandler) serveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	conn := newWebConn(fmt.Sprintf("sse:%d", atomic.AddUint64(&connections, 1)), r, "sse")
+	if !h.serving(conn) {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.served(conn)
+
+	var params httpPostBody
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		params, err = getParams(r.URL.Query())
+	case http.MethodPost:
+		if r.Body == nil {
+			err = errors.New("request must include a query")
+		} else {
+			err = json.NewDecoder(r.Body).Decode(&params)
+		}
+	default:
+		err = errors.New("request must be a GET or a POST")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	stream := &sseStream{w: w, flusher: flusher}
+
+	var query *graphql.Query
+	if err == nil {
+		query, err = h.subscription(r.Context(), params)
+	}
+	if err != nil {
+		stream.next(nil, err)
+		stream.complete()
+		return
+	}
+
+	// Every root field is executed on its own, so that a field without an update does not hold back the others.
+	// The root fields selected through fragments are executed like the others.
+	selections, err := graphql.Flatten(query.SelectionSet)
+	if err != nil {
+		stream.next(nil, err)
+		stream.complete()
+		return
+	}
+	queries := make([]*graphql.Query, 0, len(selections))
+	fields := make([][]string, 0, len(selections))
+	for _, selection := range selections {
+		selectionSet := &graphql.SelectionSet{
+			Selections: []*graphql.Selection{selection},
+		}
+		queries = append(queries, &graphql.Query{Name: query.Name, Kind: query.Kind, SelectionSet: selectionSet})
+		fields = append(fields, rootFields(selectionSet))
+	}
+
+	// A slow client is disconnected by ending its stream.
+	var sub *subscriber
+	sub = h.newSubscriber(rootFields(query.SelectionSet), h.filter(r.Context(), query), func() {
+		sub.stop()
+	})
+	conn.start(h.sessions, SubscriptionInfo{
+		ID:            conn.id,
+		OperationName: query.Name,
+		Fields:        sub.fields,
+		Started:       time.Now(),
+	}, []*subscriber{sub})
+	defer func() {
+		sub.stop()
+		h.sessions.remove(conn.id, conn.id, sub)
+		conn.end(conn.id)
+		conn.subscriptions.Done()
+	}()
+
+	stream.flush()
+
+	var heartbeat <-chan time.Time
+	if h.keepAlive > 0 {
+		ticker := time.NewTicker(h.keepAlive)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	var expired <-chan time.Time
+	if h.maxLifetime > 0 {
+		timer := time.NewTimer(h.maxLifetime)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat:
+			if !stream.heartbeat() {
+				return
+			}
+
+		case <-expired:
+			stream.complete()
+			return
+
+		case <-sub.done:
+			// The event source stopped, or the handler is shut down.
+			stream.complete()
+			return
+
+		case msg := <-sub.events:
+			for i, query := range queries {
+				if !routed(msg, fields[i]) {
+					continue
+				}
+				res, err := h.executor.Execute(r.Context(), h.schema.Subscription, &schemabuilder.Subscription{Payload: msg.Payload}, query)
+				if err == graphql.ErrNoUpdate {
+					continue
+				}
+				if !stream.next(res, err) {
+					return
+				}
+				// The errors of some of the fields are sent along with the data, and the stream goes on.
+				if err != nil && res == nil {
+					stream.complete()
+					return
+				}
+			}
+		}
+	}
+}
+
+// sseStream writes the events of a stream.
+type sseStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// next writes a result, and returns false when the stream is closed.
+func (s *sseStream) next(value interface{}, err error) bool {
+	payload, err := json.Marshal(newHTTPResponse(value, err))
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return s.write("event: next\ndata: " + string(payload) + "\n\n")
+}
+
+func (s *sseStream) complete() bool {
+	return s.write("event: complete\ndata:\n\n")
+}
+
+// heartbeat writes a comment, which clients ignore.
+func (s *sseStream) heartbeat() bool {
+	return s.write(":\n\n")
+}
+
+func (s *sseStream) write(event string) bool {
+	if _, err := s.w.Write([]byte(event)); err != nil {
+		return false
+	}
+	s.flush()
+	return true
+}
+
+func (s *sseStream) flush() {
+	s.flusher.Flush()
+}
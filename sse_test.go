@@ -0,0 +1,105 @@
+package jaal_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"go.appointy.com/jaal"
+)
+
+// readEvent reads an event of an event stream.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+func TestSubSSE(t *testing.T) {
+	server, _, topic := testSubServer(t, jaal.WithKeepAlive(50*time.Millisecond))
+	defer server.Close()
+
+	// The root fields selected through fragments are delivered as well.
+	for _, query := range []string{
+		"subscription { event }",
+		"subscription { ... on Subscription { event } }",
+		"subscription { ...Event } fragment Event on Subscription { event }",
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequest("GET", server.URL+"?query="+url.QueryEscape(query), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("expected an event stream, but received %s", contentType)
+		}
+
+		r := bufio.NewReader(resp.Body)
+		if diff := pretty.Compare(readEvent(t, r), ":"); diff != "" {
+			t.Errorf("expected a heartbeat, but received %s", diff)
+		}
+
+		done := make(chan struct{})
+		publishUntil(t, topic, "hello", done)
+		for {
+			event := readEvent(t, r)
+			if event == ":" {
+				continue
+			}
+			close(done)
+			if diff := pretty.Compare(event, "event: next\ndata: {\"data\":{\"event\":\"hello\"},\"errors\":null}"); diff != "" {
+				t.Errorf("expected next for %s, but received %s", query, diff)
+			}
+			break
+		}
+	}
+}
+
+func TestSubSSEErrors(t *testing.T) {
+	server, _, _ := testSubServer(t)
+	defer server.Close()
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader(`{"query": "{ mirror(value: 1) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	if diff := pretty.Compare(readEvent(t, r), "event: next\ndata: {\"data\":null,\"errors\":[{\"message\":\"query operations cannot be subscribed to\",\"extensions\":{\"code\":\"Unknown\"},\"paths\":[]}]}"); diff != "" {
+		t.Errorf("expected next, but received %s", diff)
+	}
+	if diff := pretty.Compare(readEvent(t, r), "event: complete\ndata:"); diff != "" {
+		t.Errorf("expected complete, but received %s", diff)
+	}
+}
@@ -0,0 +1,116 @@
+package jaal
+
+// This file contains the trusted documents mode, in which a handler only executes the operations of a manifest
+// registered up front, and refuses arbitrary queries.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+)
+
+// TrustedDocuments are the documents a handler is restricted to with WithTrustedDocuments. Clients refer to
+// a document by its id, sent as the documentId parameter or as the hash of a persisted query.
+type TrustedDocuments struct {
+	documents map[string]*graphql.Document
+}
+
+// NewTrustedDocuments parses the documents of a manifest, which maps ids to documents, and validates their
+// operations against schema with graphql.SpecifiedRules. They are validated again with the rules and limits of
+// the handler they are attached to.
+func NewTrustedDocuments(schema *graphql.Schema, manifest map[string]string) (*TrustedDocuments, error) {
+	ids := make([]string, 0, len(manifest))
+	for id := range manifest {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	t := &TrustedDocuments{documents: make(map[string]*graphql.Document, len(manifest))}
+	for _, id := range ids {
+		document, err := graphql.ParseDocument(manifest[id])
+		if err != nil {
+			return nil, fmt.Errorf("trusted document %s: %v", id, err)
+		}
+		t.documents[id] = document
+	}
+	if err := t.validate(schema, graphql.SpecifiedRules); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// validate checks the operations of the documents against schema with rules.
+func (t *TrustedDocuments) validate(schema *graphql.Schema, rules []graphql.Rule) error {
+	ids := make([]string, 0, len(t.documents))
+	for id := range t.documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		document := t.documents[id]
+		for _, name := range document.OperationNames() {
+			query, err := document.Operation(name, nil)
+			if err == nil {
+				err = graphql.Validate(schema, query, rules...)
+			}
+			if err != nil {
+				return fmt.Errorf("trusted document %s: %v", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadTrustedDocuments reads a JSON manifest mapping ids to documents from a file, and validates it like
+// NewTrustedDocuments.
+func LoadTrustedDocuments(schema *graphql.Schema, path string) (*TrustedDocuments, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return NewTrustedDocuments(schema, manifest)
+}
+
+// WithTrustedDocuments restricts a handler to the operations of documents. Requests must refer to a document by
+// its id, and queries sent as text are refused.
+//
+// The documents are validated with the rules and limits of the handler when it is created, which panics if an
+// operation violates them, as it would fail on every request.
+func WithTrustedDocuments(documents *TrustedDocuments) HandlerOption {
+	return func(h *handlerOptions) {
+		h.TrustedDocuments = documents
+	}
+}
+
+// document returns the trusted document a request refers to.
+func (t *TrustedDocuments) document(params httpPostBody) (*graphql.Document, error) {
+	ids := []string{params.DocumentID}
+	if params.DocumentID == "" && params.Extensions != nil && params.Extensions.PersistedQuery != nil {
+		hash := params.Extensions.PersistedQuery.Sha256Hash
+		ids = []string{hash, "sha256:" + hash}
+	}
+	if ids[0] == "" {
+		return nil, &jerrors.Error{
+			Message:    "only trusted documents can be executed",
+			Extensions: &jerrors.Extension{Code: "PermissionDenied"},
+			Paths:      []string{},
+		}
+	}
+
+	for _, id := range ids {
+		if document, ok := t.documents[id]; ok {
+			return document, nil
+		}
+	}
+	return nil, persistedQueryError("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND")
+}
@@ -0,0 +1,162 @@
+package jaal
+
+// This file contains the file uploads, which follow the GraphQL multipart request specification: the operations
+// are sent as the "operations" part of a multipart/form-data request, the files as other parts, and the "map"
+// part tells which variables hold which files.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+const (
+	// defaultMaxUploadSize is the maximum size of a multipart request, unless set with WithMaxUploadSize.
+	defaultMaxUploadSize = 32 << 20
+	// defaultUploadMemory is the size of the files kept in memory, unless set with WithUploadMemory.
+	defaultUploadMemory = 8 << 20
+)
+
+// WithMaxUploadSize sets the maximum size in bytes of a multipart request, including its files. It defaults to
+// 32 MB.
+func WithMaxUploadSize(n int64) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxUploadSize = n
+	}
+}
+
+// WithUploadMemory sets the number of bytes of the files of a multipart request kept in memory. The rest is
+// streamed to temporary files, which are removed once the request is served. It defaults to 8 MB.
+func WithUploadMemory(n int64) HandlerOption {
+	return func(h *handlerOptions) {
+		h.UploadMemory = n
+	}
+}
+
+// isMultipart checks if a request is a multipart request.
+func isMultipart(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readMultipart reads a multipart request, and returns its operations and its form. The files of the form must be
+// removed with RemoveAll once the request is served.
+func (h *httpHandler) readMultipart(w http.ResponseWriter, r *http.Request) (json.RawMessage, *multipart.Form, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize)
+	if err := r.ParseMultipartForm(h.uploadMemory); err != nil {
+		return nil, nil, fmt.Errorf("failed to read multipart request: %v", err)
+	}
+
+	operations := r.MultipartForm.Value["operations"]
+	if len(operations) == 0 {
+		_ = r.MultipartForm.RemoveAll()
+		return nil, nil, errors.New("multipart request must include operations")
+	}
+	return json.RawMessage(operations[0]), r.MultipartForm, nil
+}
+
+// bindUploads sets the variables of the operations to the files of a multipart request, as mapped by its "map"
+// part. The returned function closes the files.
+func bindUploads(form *multipart.Form, batch []httpPostBody, batched bool) (func(), error) {
+	var fileMap map[string][]string
+	if values := form.Value["map"]; len(values) > 0 {
+		if err := json.Unmarshal([]byte(values[0]), &fileMap); err != nil {
+			return nil, fmt.Errorf("multipart map must be a JSON object: %v", err)
+		}
+	}
+
+	var files []multipart.File
+	closeFiles := func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}
+
+	for name, paths := range fileMap {
+		headers := form.File[name]
+		if len(headers) == 0 {
+			closeFiles()
+			return nil, fmt.Errorf("multipart request has no file %s", name)
+		}
+		header := headers[0]
+
+		// Every variable gets its own reader, as a file may be mapped to several variables.
+		for _, path := range paths {
+			file, err := header.Open()
+			if err != nil {
+				closeFiles()
+				return nil, err
+			}
+			files = append(files, file)
+
+			upload := &schemabuilder.Upload{
+				Reader:      file,
+				Filename:    header.Filename,
+				ContentType: header.Header.Get("Content-Type"),
+				Size:        header.Size,
+			}
+			if err := setUpload(batch, batched, path, upload); err != nil {
+				closeFiles()
+				return nil, err
+			}
+		}
+	}
+	return closeFiles, nil
+}
+
+// setUpload sets the variable at path, such as "variables.files.0" or "0.variables.file" in a batch, to upload.
+func setUpload(batch []httpPostBody, batched bool, path string, upload *schemabuilder.Upload) error {
+	invalid := fmt.Errorf("invalid upload path %s", path)
+
+	keys := strings.Split(path, ".")
+	operation := 0
+	if batched {
+		i, err := strconv.Atoi(keys[0])
+		if err != nil || i < 0 || i >= len(batch) {
+			return invalid
+		}
+		operation = i
+		keys = keys[1:]
+	}
+	if len(keys) < 2 || keys[0] != "variables" {
+		return invalid
+	}
+
+	var container interface{} = batch[operation].Variables
+	for i, key := range keys[1:] {
+		last := i == len(keys)-2
+		switch c := container.(type) {
+		case map[string]interface{}:
+			if c == nil {
+				return invalid
+			}
+			if last {
+				c[key] = upload
+				return nil
+			}
+			container = c[key]
+
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(c) {
+				return invalid
+			}
+			if last {
+				c[index] = upload
+				return nil
+			}
+			container = c[index]
+
+		default:
+			return invalid
+		}
+	}
+	return invalid
+}
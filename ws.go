@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"gocloud.dev/pubsub"
@@ -18,76 +20,328 @@ import (
 	"go.appointy.com/jaal/schemabuilder"
 )
 
-// HTTPSubHandler implements the handler required for executing the graphql subscriptions
-func HTTPSubHandler(schema *graphql.Schema, s *pubsub.Subscription) (http.Handler, func()) {
-	source := make(chan *event)
+// ConnectionInitFunc accepts or rejects a websocket connection with the payload of its connection_init message,
+// which usually holds the credentials of the client. It returns the context the subscriptions of the connection
+// are executed with.
+type ConnectionInitFunc func(ctx context.Context, payload map[string]interface{}) (context.Context, error)
+
+// WithConnectionInit authenticates the websocket connections with f. The connections for which f returns an
+// error are rejected, and those accepted have their subscriptions executed with the context returned by f.
+func WithConnectionInit(f ConnectionInitFunc) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ConnectionInit = f
+	}
+}
+
+// WithIdleTimeout closes the websocket connections from which nothing was received for d, including the
+// answers to the keep-alive pings. The clients of graphql-transport-ws must also send their connection_init
+// message within d. The connections are never closed for being idle by default.
+func WithIdleTimeout(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.IdleTimeout = d
+	}
+}
+
+// WithMaxConnectionLifetime completes the subscriptions of the websocket connections and event streams open for
+// longer than d, and closes them, so that the clients reconnect, possibly to another server. The connections
+// are kept open by default.
+func WithMaxConnectionLifetime(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxConnectionLifetime = d
+	}
+}
+
+// HTTPSubHandler implements the handler required for executing the graphql subscriptions. The queries and
+// mutations are routed to an HTTPHandler, and the options apply to both. The returned function starts receiving
+// the events.
+func HTTPSubHandler(schema *graphql.Schema, s *pubsub.Subscription, opts ...HandlerOption) (*SubHandler, func()) {
+	return HTTPSourceHandler(schema, PubSubSource(s), opts...)
+}
+
+// HTTPSourceHandler is an HTTPSubHandler resolving the subscriptions with the events of source. The returned
+// function starts receiving the events.
+func HTTPSourceHandler(schema *graphql.Schema, s EventSource, opts ...HandlerOption) (*SubHandler, func()) {
+	o := newHandlerOptions(opts)
+	source := make(chan *Event)
 	sessions := &sessions{
-		data:  map[string][]chan *event{},
-		chans: map[string][]chan struct{}{},
-	}
-	return &httpSubHandler{
-			handler: handler{
-				schema:   schema,
-				executor: &graphql.Executor{},
-			},
-			qmHandler: HTTPHandler(schema),
-			upgrader:  &websocket.Upgrader{},
-			source:    source,
-			sessions:  sessions,
-		}, func() {
-			go startListening(s, source, func() {
-				exit(sessions)
-			})
-			go listenSource(source, sessions)
-		}
+		data:  map[string]map[string][]*subscriber{},
+		index: map[string]map[*subscriber]struct{}{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &SubHandler{
+		handler:        o.handler(schema),
+		qmHandler:      HTTPHandler(schema, opts...),
+		upgrader:       &websocket.Upgrader{},
+		source:         source,
+		sessions:       sessions,
+		playground:     o.Playground,
+		keepAlive:      o.KeepAlive,
+		idleTimeout:    o.IdleTimeout,
+		maxLifetime:    o.MaxConnectionLifetime,
+		maxSubs:        o.MaxSubscriptions,
+		bufferSize:     o.SubscriptionBuffer,
+		overflowPolicy: o.OverflowPolicy,
+		connectionInit: o.ConnectionInit,
+		cancel:         cancel,
+		conns:          map[*webConn]struct{}{},
+	}
+	return h, func() {
+		go startListening(ctx, s, source)
+		go listenSource(source, sessions)
+	}
 }
 
-func listenSource(events chan *event, ss *sessions) {
+// listenSource delivers the events to the subscribers until the source stops, and then stops the subscribers.
+// The events are looked up by type in the index of the subscribers, and those without a type are delivered to
+// every subscriber.
+func listenSource(events chan *Event, ss *sessions) {
 	for evt := range events {
 		ss.RLock()
-		for _, v := range ss.data {
-			for _, s := range v {
-				s <- evt
+		if evt.Type == "" {
+			for _, conn := range ss.data {
+				for _, v := range conn {
+					for _, s := range v {
+						s.push(evt, ss)
+					}
+				}
+			}
+		} else {
+			for s := range ss.index[evt.Type] {
+				s.push(evt, ss)
 			}
 		}
 		ss.RUnlock()
 	}
+	exit(ss)
 }
 
-func startListening(s *pubsub.Subscription, source chan<- *event, cancel func()) {
+// startListening receives the events of s until it fails or ctx is cancelled, and then closes source.
+func startListening(ctx context.Context, s EventSource, source chan<- *Event) {
+	defer close(source)
 	for {
-		msg, err := s.Receive(context.Background())
+		evt, err := s.Receive(ctx)
 		if err != nil {
-			fmt.Println("Pubsub failed: ", err)
-			cancel()
+			fmt.Println("Event source failed: ", err)
 			return
 		}
-		msg.Ack()
 
-		source <- &event{
-			payload: msg.Body,
-			typ:     msg.Metadata["type"],
-		}
+		source <- evt
 	}
 }
 
-type httpSubHandler struct {
+// SubHandler serves the subscriptions over websockets and event streams, and routes the other requests to an
+// HTTPHandler.
+type SubHandler struct {
 	handler
 	qmHandler http.Handler
 	upgrader  *websocket.Upgrader
-	source    chan *event
+	source    chan *Event
 	sessions  *sessions
+
+	// playground serves the playground page to browsers, with the subscriptions sent to the same endpoint.
+	playground bool
+	// keepAlive is the interval of the heartbeats of the connections and event streams.
+	keepAlive time.Duration
+	// idleTimeout and maxLifetime bound the time the connections are kept open.
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	// maxSubs is the number of subscriptions a connection may run at once.
+	maxSubs int
+	// bufferSize is the number of events queued for a subscriber, and overflowPolicy applies when it is full.
+	bufferSize     int
+	overflowPolicy OverflowPolicy
+	// connectionInit authenticates the websocket connections with their connection_init payload.
+	connectionInit ConnectionInitFunc
+
+	// cancel stops receiving the events.
+	cancel context.CancelFunc
+
+	// mu guards the open connections, and the shutdown of the handler.
+	mu           sync.Mutex
+	conns        map[*webConn]struct{}
+	shuttingDown bool
+	// active counts the websocket connections and event streams being served.
+	active sync.WaitGroup
+}
+
+// Shutdown stops receiving events, completes every active subscription and closes the websocket connections
+// and event streams. It waits for them to be closed until ctx is done. The handler refuses the subscriptions
+// received afterwards.
+func (h *SubHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	conns := make([]*webConn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	h.cancel()
+	exit(h.sessions)
+	for _, conn := range conns {
+		// The event streams end with their subscription.
+		if conn.conn != nil {
+			go h.closeConnection(conn, websocket.CloseGoingAway, "server shutting down")
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-type event struct {
-	typ     string
-	payload []byte
+// serving registers a websocket connection or an event stream being served, and returns false when the handler
+// is shut down.
+func (h *SubHandler) serving(conn *webConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shuttingDown {
+		return false
+	}
+	h.active.Add(1)
+	h.conns[conn] = struct{}{}
+	return true
+}
+
+// served unregisters a websocket connection or an event stream.
+func (h *SubHandler) served(conn *webConn) {
+	h.mu.Lock()
+	delete(h.conns, conn)
+	h.mu.Unlock()
+	h.active.Done()
 }
 
 type sessions struct {
+	// dropped and slowConsumers count the events dropped and the connections closed by the overflow policy.
+	dropped       uint64
+	slowConsumers uint64
+
 	sync.RWMutex
-	data  map[string][]chan *event
-	chans map[string][]chan struct{}
+	// data holds the subscribers of the subscriptions by connection, and then by id, as the ids are chosen by
+	// the clients.
+	data map[string]map[string][]*subscriber
+	// index holds the subscribers by the root fields they select, which are the types of the events they receive.
+	index map[string]map[*subscriber]struct{}
+}
+
+// subscriber receives the events of the root fields selected by a subscription.
+type subscriber struct {
+	// dropped counts the events dropped by the overflow policy.
+	dropped uint64
+
+	// events queues the events until the subscriber takes them.
+	events chan *Event
+	// fields holds the root fields selected, which are the types of the events received.
+	fields []string
+	// filter, when set, drops the events irrelevant to the subscription before they are queued. It is called
+	// by the router, so it must not block.
+	filter func(*Event) bool
+	// done is closed once the subscriber is stopped.
+	done chan struct{}
+	once sync.Once
+
+	// policy applies to the events received with a full queue, and overflow is called once by Disconnect.
+	policy     OverflowPolicy
+	overflow   func()
+	overflowed sync.Once
+}
+
+// stop stops the subscriber, which no longer receives events.
+func (s *subscriber) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// add registers a subscriber of the subscription id of a connection.
+func (ss *sessions) add(conn, id string, s *subscriber) {
+	ss.Lock()
+	if ss.data[conn] == nil {
+		ss.data[conn] = map[string][]*subscriber{}
+	}
+	ss.data[conn][id] = append(ss.data[conn][id], s)
+	for _, field := range s.fields {
+		if ss.index[field] == nil {
+			ss.index[field] = map[*subscriber]struct{}{}
+		}
+		ss.index[field][s] = struct{}{}
+	}
+	ss.Unlock()
+}
+
+// remove unregisters a stopped subscriber of the subscription id of a connection, and reports whether it was the
+// last one.
+func (ss *sessions) remove(conn, id string, s *subscriber) bool {
+	ss.Lock()
+	defer ss.Unlock()
+
+	subscribers, ok := ss.data[conn][id]
+	if !ok {
+		return false
+	}
+	for i, v := range subscribers {
+		if v == s {
+			subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+			ss.unindex(s)
+			break
+		}
+	}
+	if len(subscribers) != 0 {
+		ss.data[conn][id] = subscribers
+		return false
+	}
+	ss.delete(conn, id)
+	return true
+}
+
+// stop stops the subscribers of the subscriptions ids of a connection. When unregister is set, the
+// subscriptions are also unregistered, so that their end is not reported to the client.
+func (ss *sessions) stop(unregister bool, conn string, ids ...string) {
+	ss.RLock()
+	for _, id := range ids {
+		for _, s := range ss.data[conn][id] {
+			s.stop()
+		}
+	}
+	ss.RUnlock()
+
+	if unregister {
+		ss.Lock()
+		for _, id := range ids {
+			ss.delete(conn, id)
+		}
+		ss.Unlock()
+	}
+}
+
+// delete unregisters the subscription id of a connection, and the connection with its last subscription.
+func (ss *sessions) delete(conn, id string) {
+	for _, s := range ss.data[conn][id] {
+		ss.unindex(s)
+	}
+	delete(ss.data[conn], id)
+	if len(ss.data[conn]) == 0 {
+		delete(ss.data, conn)
+	}
+}
+
+// unindex removes a subscriber from the index.
+func (ss *sessions) unindex(s *subscriber) {
+	for _, field := range s.fields {
+		delete(ss.index[field], s)
+		if len(ss.index[field]) == 0 {
+			delete(ss.index, field)
+		}
+	}
 }
 
 type wsMessage struct {
@@ -97,22 +351,38 @@ type wsMessage struct {
 }
 
 type gqlPayload struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
-	OpName    string                 `json:"operationName"`
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables"`
+	OpName     string                 `json:"operationName"`
+	Extensions *httpExtensions        `json:"extensions"`
+	DocumentID string                 `json:"documentId"`
 }
 
-func (h *httpSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet { // If not a subscription request route to normal handler
+func (h *SubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) { // If not a subscription request route to normal handler
+		if wantsEventStream(r) {
+			h.serveSSE(w, r)
+			return
+		}
+		if h.playground && wantsPlayground(r) {
+			servePlayground(w, r.URL.Path, r.URL.Path)
+			return
+		}
 		h.qmHandler.ServeHTTP(w, r)
 		return
 	}
 	log.Println("Request Headers:", r.Header)
 
+	protocol := negotiateProtocol(websocket.Subprotocols(r))
+	if protocol == nil {
+		http.Error(w, "unsupported websocket subprotocol", http.StatusBadRequest)
+		return
+	}
+
 	// Check origin and set response headers
 	h.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
 	res := http.Header{}
-	res["Sec-Websocket-Protocol"] = []string{"graphql-ws"}
+	res["Sec-Websocket-Protocol"] = []string{protocol.name}
 
 	con, err := h.upgrader.Upgrade(w, r, res)
 	if err != nil {
@@ -121,42 +391,82 @@ func (h *httpSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer con.Close()
 
-	if con.Subprotocol() != "graphql-ws" {
-		fmt.Println("invalid subprotocol")
+	conn := newWebConn(fmt.Sprintf("ws:%d", atomic.AddUint64(&connections, 1)), r, protocol.name)
+	conn.conn = con
+	conn.protocol = protocol
+	if !h.serving(conn) {
+		conn.close(websocket.CloseGoingAway, "server shutting down")
 		return
 	}
+	defer h.served(conn)
 
-	var msg wsMessage
+	// The subscriptions of a client which went away are stopped, without reporting their end.
+	defer func() {
+		h.sessions.stop(true, conn.id, conn.activeIDs()...)
+		conn.subscriptions.Wait()
+	}()
 
-	if err := con.ReadJSON(&msg); err != nil {
-		fmt.Println("failed to parse websocket message: ", err)
+	if h.idleTimeout > 0 {
+		conn.extendDeadline(h.idleTimeout)
+		con.SetPongHandler(func(string) error {
+			conn.extendDeadline(h.idleTimeout)
+			return nil
+		})
+	}
+
+	ctx, ok := h.initConnection(r.Context(), conn)
+	if !ok {
 		return
 	}
-	conn := &webConn{conn: con}
-	if msg.Type != "connection_init" {
-		if err := writeResponse(conn, "connection_error", "", nil, errors.New("expected init message")); err != nil {
-			fmt.Println(err)
-			return
-		}
+
+	done := make(chan struct{})
+	defer close(done)
+	if h.keepAlive > 0 {
+		go h.keepConnectionAlive(conn, done)
 	}
-	if err := writeResponse(conn, "connection_ack", "", nil, nil); err != nil {
-		fmt.Println(err)
-		return
+	if h.maxLifetime > 0 {
+		timer := time.AfterFunc(h.maxLifetime, func() {
+			h.closeConnection(conn, websocket.CloseNormalClosure, "connection lifetime exceeded")
+		})
+		defer timer.Stop()
 	}
+
 loop:
 	for {
 		var data wsMessage
 		if err := con.ReadJSON(&data); err != nil {
+			if _, ok := err.(*websocket.CloseError); ok || conn.isClosing() {
+				fmt.Println(err)
+				return
+			}
+			if isTimeout(err) {
+				fmt.Println(err)
+				conn.close(websocket.CloseNormalClosure, "idle timeout")
+				return
+			}
+			if protocol == graphqlTransportWS {
+				fmt.Println(err)
+				conn.close(closeInvalidMessage, "invalid message")
+				return
+			}
 			if err := writeResponse(conn, "connection_error", "", nil, err); err != nil {
 				fmt.Println(err)
 				return
 			}
 			fmt.Println(err)
 		}
+		if h.idleTimeout > 0 {
+			conn.extendDeadline(h.idleTimeout)
+		}
+
 		switch data.Type {
-		case "start":
+		case protocol.start:
 			var gql gqlPayload
 			if err := json.Unmarshal(data.Payload, &gql); err != nil {
+				if protocol == graphqlTransportWS {
+					conn.close(closeInvalidMessage, "invalid subscribe payload")
+					return
+				}
 				if err := writeResponse(conn, "connection_error", "", nil, err); err != nil {
 					fmt.Println(err)
 					return
@@ -164,102 +474,464 @@ loop:
 				fmt.Println(err)
 				return
 			}
-			query, err := graphql.Parse(gql.Query, gql.Variables)
+			if protocol == graphqlTransportWS {
+				if data.Id == "" {
+					conn.close(closeInvalidMessage, "subscribe message must have an id")
+					return
+				}
+				if conn.active(data.Id) {
+					conn.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", data.Id))
+					return
+				}
+			}
+			if h.maxSubs > 0 && conn.count() >= h.maxSubs && !conn.active(data.Id) {
+				err := fmt.Errorf("connection cannot run more than %d subscriptions", h.maxSubs)
+				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
+					fmt.Println(er)
+					return
+				}
+				fmt.Println(err)
+				continue
+			}
+			query, err := h.subscription(ctx, httpPostBody{
+				Query:         gql.Query,
+				Variables:     gql.Variables,
+				OperationName: gql.OpName,
+				Extensions:    gql.Extensions,
+				DocumentID:    gql.DocumentID,
+			})
 			if err != nil {
 				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
-					fmt.Println(err)
+					fmt.Println(er)
 					return
 				}
 				fmt.Println(err)
-				return
+				continue
 			}
-			schema := h.schema.Subscription
-			if err := graphql.ValidateQuery(r.Context(), schema, query.SelectionSet); err != nil {
+			// The root fields selected through fragments are subscribed to like the others.
+			selections, err := graphql.Flatten(query.SelectionSet)
+			if err != nil {
 				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
 					fmt.Println(er)
 					return
 				}
 				fmt.Println(err)
-				return
+				continue
 			}
-			for _, v := range query.SelectionSet.Selections {
-				end := make(chan struct{}, 1)
+			// The subscribers are registered with the connection at once, so that closing it stops all of them.
+			queries := make([]*graphql.Query, 0, len(selections))
+			subs := make([]*subscriber, 0, len(selections))
+			for _, v := range selections {
 				modQuery := &graphql.Query{
 					Name: query.Name,
 					Kind: query.Kind,
 					SelectionSet: &graphql.SelectionSet{
 						Selections: []*graphql.Selection{v},
-						Fragments:  query.SelectionSet.Fragments,
 					},
 				}
-				go func(conn *webConn, data *wsMessage, schema graphql.Type, query *graphql.Query, end chan struct{}, w http.ResponseWriter, r *http.Request) {
-					if err := h.serveHTTP(conn, *data, schema, query, end, w, r); err != nil {
+				queries = append(queries, modQuery)
+				subs = append(subs, h.newSubscriber(rootFields(modQuery.SelectionSet), h.filter(ctx, modQuery), func() {
+					h.closeConnection(conn, websocket.CloseTryAgainLater, "slow consumer")
+				}))
+			}
+			info := SubscriptionInfo{
+				ID:            data.Id,
+				OperationName: query.Name,
+				Fields:        rootFields(query.SelectionSet),
+				Started:       time.Now(),
+			}
+			if !conn.start(h.sessions, info, subs) {
+				return
+			}
+			schema := h.schema.Subscription
+			for i, sub := range subs {
+				go func(conn *webConn, data *wsMessage, schema graphql.Type, query *graphql.Query, sub *subscriber) {
+					defer conn.subscriptions.Done()
+					if err := h.serveHTTP(ctx, conn, *data, schema, query, sub); err != nil {
 						fmt.Println("Id:", data.Id, ": terminated: ", err)
 					}
-					h.sessions.Lock()
-					if _, ok := h.sessions.data[data.Id]; ok {
+					sub.stop()
+					if h.sessions.remove(conn.id, data.Id, sub) {
+						conn.end(data.Id)
 						if err := writeResponse(conn, "complete", data.Id, nil, nil); err != nil {
 							fmt.Println(err)
 						}
 						fmt.Println("Id:", data.Id, ": terminated.")
 					}
-					delete(h.sessions.data, data.Id)
-					delete(h.sessions.chans, data.Id)
-					h.sessions.Unlock()
-				}(conn, &data, schema, modQuery, end, w, r)
+				}(conn, &data, schema, queries[i], sub)
 			}
-		case "stop":
-			h.sessions.RLock()
-			for _, v := range h.sessions.chans[data.Id] {
-				v <- struct{}{}
-			}
-			h.sessions.RUnlock()
+		case protocol.stop:
+			conn.end(data.Id)
+			h.sessions.stop(true, conn.id, data.Id)
 		case "connection_terminate":
-			exit(h.sessions)
-			break loop
+			if protocol == graphqlWS {
+				break loop
+			}
+			conn.close(closeInvalidMessage, "invalid message type connection_terminate")
+			return
+		case "ping":
+			if protocol == graphqlTransportWS {
+				if err := writeResponse(conn, "pong", "", nil, nil); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+		case "pong":
+		case "connection_init":
+			if protocol == graphqlTransportWS {
+				conn.close(closeTooManyInitRequests, "Too many initialisation requests")
+				return
+			}
 		default:
+			if protocol == graphqlTransportWS {
+				conn.close(closeInvalidMessage, fmt.Sprintf("invalid message type %s", data.Type))
+				return
+			}
 		}
 	}
 }
 
+// keepConnectionAlive sends a keep-alive message and a ping every keepAlive interval, until done is closed. The
+// pongs of the client prove it is still connected when it does not send messages.
+func (h *SubHandler) keepConnectionAlive(conn *webConn, done chan struct{}) {
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := writeResponse(conn, conn.protocol.keepAlive, "", nil, nil); err != nil {
+				fmt.Println(err)
+				return
+			}
+			if err := conn.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+	}
+}
+
+// closeConnection completes the subscriptions of a connection, and then closes it with a close code and reason.
+func (h *SubHandler) closeConnection(conn *webConn, code int, reason string) {
+	if !conn.closing() {
+		return
+	}
+
+	h.sessions.stop(false, conn.id, conn.activeIDs()...)
+	conn.subscriptions.Wait()
+	conn.close(code, reason)
+	_ = conn.conn.Close()
+}
+
+// isTimeout checks if a read failed because of the idle timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// subscription returns the validated subscription operation of a request.
+func (h *handler) subscription(ctx context.Context, params httpPostBody) (*graphql.Query, error) {
+	prepared, err := h.prepare(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	query := prepared.Bind(params.Variables)
+
+	if query.Kind != "subscription" {
+		return nil, fmt.Errorf("%s operations cannot be subscribed to", query.Kind)
+	}
+	if err := h.validateBound(query); err != nil {
+		return nil, err
+	}
+	if err := graphql.ValidateVariables(h.schema, query, params.Variables); err != nil {
+		return nil, err
+	}
+	if err := graphql.ValidateQuery(ctx, h.schema.Subscription, query.SelectionSet); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// initConnection waits for the connection_init message of the client, and acknowledges it once accepted by the
+// connection init hook. It returns the context of the subscriptions of the connection, and false when the
+// connection must be closed.
+func (h *SubHandler) initConnection(ctx context.Context, conn *webConn) (context.Context, bool) {
+	var msg wsMessage
+	for {
+		if err := conn.conn.ReadJSON(&msg); err != nil {
+			fmt.Println("failed to parse websocket message: ", err)
+			if _, ok := err.(*websocket.CloseError); !ok && conn.protocol == graphqlTransportWS {
+				if isTimeout(err) {
+					conn.close(closeInitTimeout, "Connection initialisation timeout")
+				} else {
+					conn.close(closeInvalidMessage, "invalid message")
+				}
+			}
+			return nil, false
+		}
+		if msg.Type == "connection_init" {
+			break
+		}
+
+		if conn.protocol == graphqlWS {
+			if err := writeResponse(conn, "connection_error", "", nil, errors.New("expected init message")); err != nil {
+				fmt.Println(err)
+			}
+			return nil, false
+		}
+
+		// The clients of graphql-transport-ws may ping before they are acknowledged, but may not subscribe.
+		switch msg.Type {
+		case "ping":
+			if err := writeResponse(conn, "pong", "", nil, nil); err != nil {
+				fmt.Println(err)
+				return nil, false
+			}
+		case "pong":
+		case graphqlTransportWS.start:
+			conn.close(closeUnauthorized, "Unauthorized")
+			return nil, false
+		default:
+			conn.close(closeInvalidMessage, fmt.Sprintf("invalid message type %s", msg.Type))
+			return nil, false
+		}
+	}
+
+	if h.connectionInit != nil {
+		var payload map[string]interface{}
+		if len(msg.Payload) > 0 {
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				h.rejectConnection(conn, closeInvalidMessage, "invalid connection_init payload", err)
+				return nil, false
+			}
+		}
+
+		initCtx, err := h.connectionInit(ctx, payload)
+		if err != nil {
+			h.rejectConnection(conn, closeForbidden, "Forbidden", err)
+			return nil, false
+		}
+		if initCtx != nil {
+			ctx = initCtx
+		}
+	}
+
+	if err := writeResponse(conn, "connection_ack", "", nil, nil); err != nil {
+		fmt.Println(err)
+		return nil, false
+	}
+	return ctx, true
+}
+
+// rejectConnection refuses the connection_init message of a client, with a connection_error message or a close
+// code depending on the subprotocol.
+func (h *SubHandler) rejectConnection(conn *webConn, code int, reason string, err error) {
+	fmt.Println("connection rejected:", err)
+	if conn.protocol == graphqlTransportWS {
+		conn.close(code, reason)
+		return
+	}
+	if err := writeResponse(conn, "connection_error", "", nil, err); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// exit stops every subscriber, which completes their subscriptions.
 func exit(ss *sessions) {
 	ss.RLock()
-	for _, v := range ss.chans {
-		for _, s := range v {
-			s <- struct{}{}
+	for _, conn := range ss.data {
+		for _, v := range conn {
+			for _, s := range v {
+				s.stop()
+			}
 		}
 	}
-	for _, v := range ss.data {
-		for _, s := range v {
-			close(s)
+	ss.RUnlock()
+}
+
+// wsProtocol holds the message types which differ between the websocket subprotocols.
+type wsProtocol struct {
+	name string
+	// start and stop are sent by the client to start and stop an operation, data by the server with a result.
+	start, stop, data string
+	// keepAlive is sent by the server to keep the connection alive.
+	keepAlive string
+}
+
+var (
+	// graphqlWS is the legacy subprotocol of subscriptions-transport-ws.
+	graphqlWS = &wsProtocol{name: "graphql-ws", start: "start", stop: "stop", data: "data", keepAlive: "ka"}
+	// graphqlTransportWS is the subprotocol of graphql-ws, which closes the connection with a code on errors.
+	graphqlTransportWS = &wsProtocol{name: "graphql-transport-ws", start: "subscribe", stop: "complete", data: "next", keepAlive: "ping"}
+)
+
+// The close codes of graphql-transport-ws.
+const (
+	closeInvalidMessage      = 4400
+	closeUnauthorized        = 4401
+	closeForbidden           = 4403
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+)
+
+// negotiateProtocol returns the subprotocol to use with a client offering protocols, preferring
+// graphql-transport-ws. The clients offering no subprotocol speak graphql-ws.
+func negotiateProtocol(protocols []string) *wsProtocol {
+	if len(protocols) == 0 {
+		return graphqlWS
+	}
+	for _, protocol := range []*wsProtocol{graphqlTransportWS, graphqlWS} {
+		for _, name := range protocols {
+			if name == protocol.name {
+				return protocol
+			}
 		}
 	}
-	ss.RUnlock()
+	return nil
 }
 
+// webConn is a websocket connection, or an event stream, which has no websocket connection.
 type webConn struct {
 	sync.Mutex
-	conn *websocket.Conn
+	conn     *websocket.Conn
+	protocol *wsProtocol
+
+	// id scopes the subscriptions of the connection, described with the transport, remoteAddr and started.
+	id         string
+	transport  string
+	remoteAddr string
+	started    time.Time
+
+	// subscriptions counts the running subscribers of the connection.
+	subscriptions sync.WaitGroup
+
+	// mu guards the active subscriptions, and whether the connection is being closed.
+	mu         sync.Mutex
+	subs       map[string]*connSubscription
+	closingNow bool
+}
+
+// connSubscription is an active subscription of a connection.
+type connSubscription struct {
+	info        SubscriptionInfo
+	subscribers []*subscriber
+}
+
+// close closes the connection with a close code and reason.
+func (w *webConn) close(code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := w.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// extendDeadline closes the connection when nothing is received for d.
+func (w *webConn) extendDeadline(d time.Duration) {
+	if err := w.conn.SetReadDeadline(time.Now().Add(d)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// start registers the subscribers of a subscription, and returns false when the connection is being closed.
+func (w *webConn) start(ss *sessions, info SubscriptionInfo, subs []*subscriber) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closingNow {
+		return false
+	}
+	if _, ok := w.subs[info.ID]; !ok {
+		w.subs[info.ID] = &connSubscription{info: info}
+	}
+	w.subs[info.ID].subscribers = append(w.subs[info.ID].subscribers, subs...)
+	w.subscriptions.Add(len(subs))
+	for _, sub := range subs {
+		ss.add(w.id, info.ID, sub)
+	}
+	return true
+}
+
+// end unregisters a subscription.
+func (w *webConn) end(id string) {
+	w.mu.Lock()
+	delete(w.subs, id)
+	w.mu.Unlock()
+}
+
+// active checks if the subscription id is running.
+func (w *webConn) active(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, ok := w.subs[id]
+	return ok
+}
+
+// count returns the number of subscriptions running.
+func (w *webConn) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.subs)
+}
+
+func (w *webConn) activeIDs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ids := make([]string, 0, len(w.subs))
+	for id := range w.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// closing marks the connection as being closed, and returns false when it already was.
+func (w *webConn) closing() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closingNow {
+		return false
+	}
+	w.closingNow = true
+	return true
+}
+
+func (w *webConn) isClosing() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closingNow
 }
 
 func writeResponse(w *webConn, typ, id string, r interface{}, er error) error {
 	var payload []byte
 	var err error
 	if typ == "data" {
-		if er != nil {
-			payload, err = json.Marshal(httpResponse{Data: r, Errors: []*jerrors.Error{jerrors.ConvertError(er)}})
-			if err != nil {
-				return err
-			}
-		} else {
-			payload, err = json.Marshal(httpResponse{Data: r, Errors: []*jerrors.Error{}})
-			if err != nil {
-				return err
-			}
+		response := newHTTPResponse(r, er)
+		if response.Errors == nil {
+			response.Errors = []*jerrors.Error{}
+		}
+		payload, err = json.Marshal(response)
+		if err != nil {
+			return err
+		}
+	} else if typ == "error" && w.protocol == graphqlTransportWS {
+		payload, err = json.Marshal(newHTTPResponse(nil, er).Errors)
+		if err != nil {
+			return err
 		}
 	} else if typ == "error" || typ == "connection_error" {
-		str := strings.Replace(er.Error(), "\"", "\\\"", -1)
-		payload = json.RawMessage("{ \"error\" : \"" + str + "\"}")
+		payload, err = json.Marshal(map[string]string{"error": er.Error()})
+		if err != nil {
+			return err
+		}
+	}
+	if typ == "data" {
+		typ = w.protocol.data
 	}
 	res := wsMessage{
 		Type:    typ,
@@ -275,54 +947,24 @@ func writeResponse(w *webConn, typ, id string, r interface{}, er error) error {
 	return nil
 }
 
-func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema graphql.Type, query *graphql.Query, end chan struct{}, w http.ResponseWriter, r *http.Request) error {
-	sid := data.Id
-	sess := make(chan *event)
-	h.sessions.Lock()
-	h.sessions.data[sid] = append(h.sessions.data[sid], sess)
-	h.sessions.chans[sid] = append(h.sessions.chans[sid], end)
-	h.sessions.Unlock()
-
-	cls := func(ss *sessions, sid string) {
-		ss.Lock()
-		for _, v := range ss.data[sid] {
-			close(v)
-			for range v {
-			}
-		}
-		for _, v := range ss.chans[sid] {
-			close(v)
-			for range v {
-			}
-		}
-		ss.Unlock()
-	}
-
-	// Listening on usrChannel for any source event of subType
-	for msg := range sess {
+// serveHTTP executes a subscription for the events received by sub, until it is stopped.
+func (h *SubHandler) serveHTTP(ctx context.Context, conn *webConn, data wsMessage, schema graphql.Type, query *graphql.Query, sub *subscriber) error {
+	for {
 		select {
-		case <-end:
-			cls(h.sessions, sid)
+		case <-sub.done:
 			return nil
-		default:
-			if err := func() error {
-				res, err := h.executor.Execute(r.Context(), schema, &schemabuilder.Subscription{msg.payload}, query)
-				if err == graphql.ErrNoUpdate {
-					return nil
-				}
-				rer := err
-				if err := writeResponse(conn, "data", data.Id, res, rer); err != nil {
-					return err
-				}
-				if rer != nil {
-					return err
-				}
-				return nil
-			}(); err != nil {
-				cls(h.sessions, sid)
+		case msg := <-sub.events:
+			res, err := h.executor.Execute(ctx, schema, &schemabuilder.Subscription{Payload: msg.Payload}, query)
+			if err == graphql.ErrNoUpdate {
+				continue
+			}
+			if er := writeResponse(conn, "data", data.Id, res, err); er != nil {
+				return er
+			}
+			// The errors of some of the fields are sent along with the data, and the subscription goes on.
+			if err != nil && res == nil {
 				return err
 			}
 		}
 	}
-	return nil
 }
@@ -0,0 +1,828 @@
+package jaal_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kylelemons/godebug/pretty"
+	"gocloud.dev/pubsub"
+	"gocloud.dev/pubsub/mempubsub"
+
+	"go.appointy.com/jaal"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+type userKey struct{}
+
+// testSubServer starts a server with a subscription to the events published on the returned topic.
+func testSubServer(t *testing.T, opts ...jaal.HandlerOption) (*httptest.Server, *jaal.SubHandler, *pubsub.Topic) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	subscription := schema.Subscription()
+	subscription.FieldFunc("event", func(source *schemabuilder.Subscription) string {
+		return string(source.Payload)
+	})
+	subscription.FieldFunc("user", func(ctx context.Context, source *schemabuilder.Subscription) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return user
+	})
+
+	topic := mempubsub.NewTopic()
+	handler, start := jaal.HTTPSubHandler(schema.MustBuild(), mempubsub.NewSubscription(topic, time.Second), opts...)
+	start()
+
+	return httptest.NewServer(handler), handler, topic
+}
+
+func dialSubServer(t *testing.T, server *httptest.Server, protocols ...string) *websocket.Conn {
+	dialer := websocket.Dialer{Subprotocols: protocols}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) string {
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(message))
+}
+
+func writeMessage(t *testing.T, conn *websocket.Conn, message string) {
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// publishUntil publishes events until done is closed, as the events published before a subscription is
+// registered are not received.
+func publishUntil(t *testing.T, topic *pubsub.Topic, body string, done chan struct{}) {
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				if err := topic.Send(context.Background(), &pubsub.Message{Body: []byte(body)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+func TestSubGraphQLTransportWS(t *testing.T) {
+	server, _, topic := testSubServer(t)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws", "graphql-ws")
+	defer conn.Close()
+
+	if conn.Subprotocol() != "graphql-transport-ws" {
+		t.Fatalf("expected graphql-transport-ws to be negotiated, but received %q", conn.Subprotocol())
+	}
+
+	writeMessage(t, conn, `{"type": "ping"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"pong"}`); diff != "" {
+		t.Errorf("expected pong, but received %s", diff)
+	}
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { evnt }"}}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"error","id":"1","payload":[{"message":"cannot query field \"evnt\" on type \"Subscription\"","extensions":{"code":"InvalidArgument"},"paths":[],"locations":[{"line":1,"column":16}]}]}`); diff != "" {
+		t.Errorf("expected error, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	publishUntil(t, topic, "hello", done)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "2", "payload": {"query": "subscription { event }"}}`)
+	message := readMessage(t, conn)
+	close(done)
+	if diff := pretty.Compare(message, `{"type":"next","id":"2","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+		t.Errorf("expected next, but received %s", diff)
+	}
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, 4429) {
+				t.Errorf("expected close code 4429, but received %v", err)
+			}
+			break
+		}
+	}
+}
+
+func TestSubGraphQLTransportWSUnauthorized(t *testing.T) {
+	server, _, _ := testSubServer(t)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, 4401) {
+		t.Errorf("expected close code 4401, but received %v", err)
+	}
+}
+
+func TestSubGraphQLWS(t *testing.T) {
+	server, _, topic := testSubServer(t)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-ws")
+	defer conn.Close()
+
+	if conn.Subprotocol() != "graphql-ws" {
+		t.Fatalf("expected graphql-ws to be negotiated, but received %q", conn.Subprotocol())
+	}
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	publishUntil(t, topic, "hello", done)
+	writeMessage(t, conn, `{"type": "start", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	message := readMessage(t, conn)
+	close(done)
+	if diff := pretty.Compare(message, `{"type":"data","id":"1","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+		t.Errorf("expected data, but received %s", diff)
+	}
+}
+
+func TestSubUnsupportedProtocol(t *testing.T) {
+	server, _, _ := testSubServer(t)
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-sse"}}
+	if _, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil); err == nil {
+		t.Error("expected the connection to be refused")
+	}
+}
+
+func TestSubConnectionInit(t *testing.T) {
+	server, _, topic := testSubServer(t, jaal.WithConnectionInit(func(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+		if payload["token"] != "secret" {
+			return nil, errors.New("invalid token")
+		}
+		return context.WithValue(ctx, userKey{}, "alice"), nil
+	}))
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init", "payload": {"token": "secret"}}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	publishUntil(t, topic, "hello", done)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { user }"}}`)
+	message := readMessage(t, conn)
+	close(done)
+	if diff := pretty.Compare(message, `{"type":"next","id":"1","payload":{"data":{"user":"alice"},"errors":[]}}`); diff != "" {
+		t.Errorf("expected next, but received %s", diff)
+	}
+
+	conn = dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init", "payload": {"token": "guess"}}`)
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, 4403) {
+		t.Errorf("expected close code 4403, but received %v", err)
+	}
+
+	conn = dialSubServer(t, server, "graphql-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_error","payload":{"error":"invalid token"}}`); diff != "" {
+		t.Errorf("expected connection_error, but received %s", diff)
+	}
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+func TestSubEventSource(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	subscription := schema.Subscription()
+	subscription.FieldFunc("event", func(source *schemabuilder.Subscription) string {
+		return "event " + string(source.Payload)
+	})
+	subscription.FieldFunc("other", func(source *schemabuilder.Subscription) string {
+		return "other " + string(source.Payload)
+	})
+
+	source := jaal.NewMemorySource()
+	handler, start := jaal.HTTPSourceHandler(schema.MustBuild(), source)
+	start()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "2", "payload": {"query": "subscription { other }"}}`)
+
+	// The events are published until both subscriptions are registered and receive one.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			typ := "event"
+			if i%2 == 1 {
+				typ = "other"
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				if err := source.Publish(context.Background(), &jaal.Event{Type: typ, Payload: []byte(typ)}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	received := map[string]bool{}
+	for len(received) < 2 {
+		message := readMessage(t, conn)
+		switch message {
+		case `{"type":"next","id":"1","payload":{"data":{"event":"event event"},"errors":[]}}`,
+			`{"type":"next","id":"2","payload":{"data":{"other":"other other"},"errors":[]}}`:
+			received[message] = true
+		default:
+			t.Fatalf("expected the events to be routed by type, but received %s", message)
+		}
+	}
+	close(done)
+
+	source.Close()
+	if err := source.Publish(context.Background(), &jaal.Event{Type: "event"}); err != jaal.ErrSourceClosed {
+		t.Errorf("expected ErrSourceClosed, but received %v", err)
+	}
+}
+
+func TestSubFilter(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	var unfiltered int32
+	subscription := schema.Subscription()
+	subscription.FieldFunc("tagged", func(source *schemabuilder.Subscription, args struct{ Tag string }) string {
+		if string(source.Payload) != args.Tag {
+			atomic.AddInt32(&unfiltered, 1)
+		}
+		return string(source.Payload)
+	}, schemabuilder.Filter(func(ctx context.Context, args struct{ Tag string }, payload []byte) bool {
+		return string(payload) == args.Tag
+	}))
+
+	source := jaal.NewMemorySource()
+	handler, start := jaal.HTTPSourceHandler(schema.MustBuild(), source)
+	start()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				if err := source.Publish(context.Background(), &jaal.Event{Type: "tagged", Payload: []byte{byte('a' + i%2)}}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	defer close(done)
+
+	// The arguments of the fields selected through fragments are filtered as well.
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { tagged(tag: \"b\") }"}}`)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "2", "payload": {"query": "subscription { ...Tagged } fragment Tagged on Subscription { tagged(tag: \"a\") }"}}`)
+	received := map[string]int{}
+	for received["1"] < 3 || received["2"] < 3 {
+		switch message := readMessage(t, conn); message {
+		case `{"type":"next","id":"1","payload":{"data":{"tagged":"b"},"errors":[]}}`:
+			received["1"]++
+		case `{"type":"next","id":"2","payload":{"data":{"tagged":"a"},"errors":[]}}`:
+			received["2"]++
+		default:
+			t.Fatalf("expected the events to be filtered, but received %s", message)
+		}
+	}
+	if n := atomic.LoadInt32(&unfiltered); n != 0 {
+		t.Errorf("expected the filtered events not to be executed, but %d were", n)
+	}
+}
+
+func TestSubFilterSignature(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	subscription := schema.Subscription()
+	subscription.FieldFunc("tagged", func(source *schemabuilder.Subscription, args struct{ Tag string }) string {
+		return string(source.Payload)
+	}, schemabuilder.Filter(func(ctx context.Context, payload []byte) bool {
+		return true
+	}))
+
+	if _, err := schema.Build(); err == nil {
+		t.Error("expected a filter without the arguments of the field to be rejected")
+	}
+}
+
+func TestSubFilterPanic(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	subscription := schema.Subscription()
+	subscription.FieldFunc("tagged", func(source *schemabuilder.Subscription, args struct{ Tag string }) string {
+		return string(source.Payload)
+	}, schemabuilder.Filter(func(ctx context.Context, args struct{ Tag string }, payload []byte) bool {
+		if string(payload) == "panic" {
+			panic("bad payload")
+		}
+		return string(payload) == args.Tag
+	}))
+
+	source := jaal.NewMemorySource()
+	handler, start := jaal.HTTPSourceHandler(schema.MustBuild(), source)
+	start()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				payload := "panic"
+				if i%2 == 1 {
+					payload = "b"
+				}
+				if err := source.Publish(context.Background(), &jaal.Event{Type: "tagged", Payload: []byte(payload)}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	defer close(done)
+
+	// The events a filter panics on are rejected, and the others are still delivered.
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { tagged(tag: \"b\") }"}}`)
+	for i := 0; i < 3; i++ {
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"next","id":"1","payload":{"data":{"tagged":"b"},"errors":[]}}`); diff != "" {
+			t.Errorf("expected next, but received %s", diff)
+		}
+	}
+}
+
+func TestSubPartialResults(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+
+	query := schema.Query()
+	query.FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+		return args.Value * -1
+	})
+
+	subscription := schema.Subscription()
+	subscription.FieldFunc("event", func(source *schemabuilder.Subscription) (*string, error) {
+		if string(source.Payload) == "fail" {
+			return nil, errors.New("failed")
+		}
+		payload := string(source.Payload)
+		return &payload, nil
+	})
+
+	source := jaal.NewMemorySource()
+	handler, start := jaal.HTTPSourceHandler(schema.MustBuild(), source)
+	start()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				payload := "fail"
+				if i%2 == 1 {
+					payload = "ok"
+				}
+				if err := source.Publish(context.Background(), &jaal.Event{Type: "event", Payload: []byte(payload)}); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	defer close(done)
+
+	// A failing field resolves to null with its error, and the subscription goes on.
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	received := map[string]int{}
+	for received["fail"] < 2 || received["ok"] < 2 {
+		switch message := readMessage(t, conn); message {
+		case `{"type":"next","id":"1","payload":{"data":{"event":null},"errors":[{"message":"failed","extensions":{"code":"Unknown"},"paths":["event"]}]}}`:
+			received["fail"]++
+		case `{"type":"next","id":"1","payload":{"data":{"event":"ok"},"errors":[]}}`:
+			received["ok"]++
+		default:
+			t.Fatalf("expected partial results, but received %s", message)
+		}
+	}
+}
+
+func TestSubKeepAlive(t *testing.T) {
+	server, _, _ := testSubServer(t, jaal.WithKeepAlive(50*time.Millisecond))
+	defer server.Close()
+
+	for protocol, keepAlive := range map[string]string{
+		"graphql-ws":           `{"type":"ka"}`,
+		"graphql-transport-ws": `{"type":"ping"}`,
+	} {
+		conn := dialSubServer(t, server, protocol)
+		writeMessage(t, conn, `{"type": "connection_init"}`)
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+			t.Errorf("expected connection_ack, but received %s", diff)
+		}
+		if diff := pretty.Compare(readMessage(t, conn), keepAlive); diff != "" {
+			t.Errorf("expected keep-alive message with %s, but received %s", protocol, diff)
+		}
+		conn.Close()
+	}
+}
+
+func TestSubIdleTimeout(t *testing.T) {
+	server, _, _ := testSubServer(t, jaal.WithIdleTimeout(100*time.Millisecond))
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, 4408) {
+		t.Errorf("expected close code 4408, but received %v", err)
+	}
+
+	conn = dialSubServer(t, server, "graphql-ws")
+	defer conn.Close()
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+	_, _, err := conn.ReadMessage()
+	if closeErr, ok := err.(*websocket.CloseError); !ok || closeErr.Code != websocket.CloseNormalClosure || closeErr.Text != "idle timeout" {
+		t.Errorf("expected connection to be closed for being idle, but received %v", err)
+	}
+}
+
+func TestSubMaxConnectionLifetime(t *testing.T) {
+	server, _, _ := testSubServer(t, jaal.WithMaxConnectionLifetime(200*time.Millisecond))
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+	writeMessage(t, conn, `{"type": "start", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"complete","id":"1"}`); diff != "" {
+		t.Errorf("expected complete, but received %s", diff)
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("expected normal closure, but received %v", err)
+	}
+}
+
+func TestSubShutdown(t *testing.T) {
+	server, handler, topic := testSubServer(t)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	publishUntil(t, topic, "hello", done)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"next","id":"1","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+		t.Errorf("expected next, but received %s", diff)
+	}
+	close(done)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- handler.Shutdown(ctx)
+	}()
+
+	// The events received before the shutdown may still be sent before the subscription completes.
+	for message := readMessage(t, conn); message != `{"type":"complete","id":"1"}`; message = readMessage(t, conn) {
+		if !strings.Contains(message, `"type":"next"`) {
+			t.Fatalf("expected complete, but received %s", message)
+		}
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected close code 1001, but received %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("expected shutdown to succeed, but received %v", err)
+	}
+
+	conn = dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected new connections to be refused, but received %v", err)
+	}
+}
+
+func TestSubSessionIsolation(t *testing.T) {
+	server, _, topic := testSubServer(t)
+	defer server.Close()
+
+	var conns []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn := dialSubServer(t, server, "graphql-transport-ws")
+		defer conn.Close()
+		writeMessage(t, conn, `{"type": "connection_init"}`)
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+			t.Errorf("expected connection_ack, but received %s", diff)
+		}
+		conns = append(conns, conn)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	publishUntil(t, topic, "hello", done)
+
+	// Both clients use the id 1, which must not collide.
+	for _, conn := range conns {
+		writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"next","id":"1","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+			t.Errorf("expected next, but received %s", diff)
+		}
+	}
+
+	writeMessage(t, conns[0], `{"type": "complete", "id": "1"}`)
+	for i := 0; i < 3; i++ {
+		if diff := pretty.Compare(readMessage(t, conns[1]), `{"type":"next","id":"1","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+			t.Errorf("expected next after the other client completed, but received %s", diff)
+		}
+	}
+}
+
+func TestSubMaxSubscriptions(t *testing.T) {
+	server, _, _ := testSubServer(t, jaal.WithMaxSubscriptions(1))
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-transport-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+	writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+	writeMessage(t, conn, `{"type": "subscribe", "id": "2", "payload": {"query": "subscription { event }"}}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"error","id":"2","payload":[{"message":"connection cannot run more than 1 subscriptions","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected error, but received %s", diff)
+	}
+}
+
+func TestSubConnections(t *testing.T) {
+	server, handler, topic := testSubServer(t)
+	defer server.Close()
+
+	conn := dialSubServer(t, server, "graphql-ws")
+	defer conn.Close()
+
+	writeMessage(t, conn, `{"type": "connection_init"}`)
+	if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+		t.Errorf("expected connection_ack, but received %s", diff)
+	}
+
+	done := make(chan struct{})
+	publishUntil(t, topic, "hello", done)
+	writeMessage(t, conn, `{"type": "start", "id": "1", "payload": {"query": "subscription Events { event }"}}`)
+	message := readMessage(t, conn)
+	close(done)
+	if !strings.Contains(message, `"type":"data"`) {
+		t.Fatalf("expected data, but received %s", message)
+	}
+
+	connections := handler.Connections()
+	if len(connections) != 1 || len(connections[0].Subscriptions) != 1 {
+		t.Fatalf("expected a connection with a subscription, but received %v", connections)
+	}
+	subscription := connections[0].Subscriptions[0]
+	if diff := pretty.Compare([]interface{}{connections[0].Protocol, subscription.ID, subscription.OperationName, subscription.Fields}, []interface{}{"graphql-ws", "1", "Events", []string{"event"}}); diff != "" {
+		t.Errorf("unexpected connections: %s", diff)
+	}
+}
+
+// waitFor waits until cond is met.
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubOverflowPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy   jaal.OverflowPolicy
+		expected []string
+	}{
+		{jaal.DropOldest, []string{
+			`{"type":"next","id":"1","payload":{"data":{"event":"1"},"errors":[]}}`,
+			`{"type":"next","id":"1","payload":{"data":{"event":"3"},"errors":[]}}`,
+		}},
+		{jaal.DropNewest, []string{
+			`{"type":"next","id":"1","payload":{"data":{"event":"1"},"errors":[]}}`,
+			`{"type":"next","id":"1","payload":{"data":{"event":"2"},"errors":[]}}`,
+		}},
+		{jaal.Disconnect, []string{
+			`{"type":"next","id":"1","payload":{"data":{"event":"1"},"errors":[]}}`,
+		}},
+	} {
+		// The first event blocks the subscription until released, so that the next ones are queued.
+		started, release := make(chan struct{}), make(chan struct{})
+		var calls int32
+		schema := schemabuilder.NewSchema()
+		schema.Query().FieldFunc("mirror", func(args struct{ Value int64 }) int64 {
+			return args.Value * -1
+		})
+		schema.Subscription().FieldFunc("event", func(source *schemabuilder.Subscription) string {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+				<-release
+			}
+			return string(source.Payload)
+		})
+
+		source := jaal.NewMemorySource()
+		handler, start := jaal.HTTPSourceHandler(schema.MustBuild(), source, jaal.WithSubscriptionBuffer(1), jaal.WithOverflowPolicy(tc.policy))
+		start()
+		server := httptest.NewServer(handler)
+
+		conn := dialSubServer(t, server, "graphql-transport-ws")
+		writeMessage(t, conn, `{"type": "connection_init"}`)
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+			t.Errorf("expected connection_ack, but received %s", diff)
+		}
+		writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+		waitFor(t, func() bool { return handler.Metrics().Subscriptions == 1 })
+
+		for _, payload := range []string{"1", "2", "3"} {
+			if err := source.Publish(context.Background(), &jaal.Event{Payload: []byte(payload)}); err != nil {
+				t.Fatal(err)
+			}
+			if payload == "1" {
+				<-started
+			}
+		}
+		if tc.policy == jaal.Disconnect {
+			waitFor(t, func() bool { return handler.Metrics().SlowConsumers == 1 })
+		} else {
+			waitFor(t, func() bool { return handler.Metrics().DroppedEvents == 1 })
+			if diff := pretty.Compare(handler.Metrics(), jaal.SubscriptionMetrics{Connections: 1, Subscriptions: 1, QueuedEvents: 1, MaxQueueDepth: 1, DroppedEvents: 1}); diff != "" {
+				t.Errorf("unexpected metrics with policy %d: %s", tc.policy, diff)
+			}
+		}
+		close(release)
+
+		for _, expected := range tc.expected {
+			if diff := pretty.Compare(readMessage(t, conn), expected); diff != "" {
+				t.Errorf("unexpected message with policy %d: %s", tc.policy, diff)
+			}
+		}
+		if tc.policy == jaal.Disconnect {
+			// The queued event may still be sent before the subscription completes.
+			for message := readMessage(t, conn); message != `{"type":"complete","id":"1"}`; message = readMessage(t, conn) {
+				if message != `{"type":"next","id":"1","payload":{"data":{"event":"2"},"errors":[]}}` {
+					t.Fatalf("expected complete, but received %s", message)
+				}
+			}
+			if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+				t.Errorf("expected close code 1013, but received %v", err)
+			}
+		}
+
+		conn.Close()
+		server.Close()
+		source.Close()
+	}
+}
+
+func TestSubBufferMinimum(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		server, _, topic := testSubServer(t, jaal.WithSubscriptionBuffer(n), jaal.WithOverflowPolicy(jaal.Disconnect))
+
+		conn := dialSubServer(t, server, "graphql-transport-ws")
+		writeMessage(t, conn, `{"type": "connection_init"}`)
+		if diff := pretty.Compare(readMessage(t, conn), `{"type":"connection_ack"}`); diff != "" {
+			t.Errorf("expected connection_ack, but received %s", diff)
+		}
+
+		// The events are queued, rather than overflowing a queue without room.
+		done := make(chan struct{})
+		publishUntil(t, topic, "hello", done)
+		writeMessage(t, conn, `{"type": "subscribe", "id": "1", "payload": {"query": "subscription { event }"}}`)
+		message := readMessage(t, conn)
+		close(done)
+		if diff := pretty.Compare(message, `{"type":"next","id":"1","payload":{"data":{"event":"hello"},"errors":[]}}`); diff != "" {
+			t.Errorf("expected next with a buffer of %d, but received %s", n, diff)
+		}
+
+		conn.Close()
+		server.Close()
+	}
+}